@@ -0,0 +1,306 @@
+package xsd
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A content model is compiled into a state machine so a document can be validated one element at a time,
+// holding only the current state of each open element. The particles become a nondeterministic automaton with
+// a transition for each element and wildcard, the deterministic states are built as elements are seen
+
+// repeatLimit is the most optional copies of a particle the automaton has, a particle that can repeat more than
+// this many times after its minOccurs is a loop with a counter
+const repeatLimit = 100
+
+// automaton recognises the sequences of child elements a content model allows
+type automaton struct {
+	xsd      *XSD
+	states   []*nfaState
+	start    *dfaState
+	final    int
+	all      *All         // An all content model is matched by counting the elements rather than by the states
+	terms    []XsdElement // The *Element and *Any particles of the content model
+	dfas     map[string]*dfaState
+	counters []*counter
+}
+
+// counter limits the repeats of a particle with a large maxOccurs. Its loop is entered by the transitions of the
+// start states and left by any transition outside the particle, which resets the count
+type counter struct {
+	max    int
+	starts map[int]bool
+	inside map[int]bool
+}
+
+type nfaState struct {
+	epsilon []int      // The states reached without an element
+	term    XsdElement // The *Element or *Any of the transition to next
+	next    int
+}
+
+// dfaState is a set of nfa states, the transitions are added for each element name seen
+type dfaState struct {
+	states []int
+	final  bool
+	next   map[QName]*dfaTransition
+}
+
+type dfaTransition struct {
+	to     *dfaState // nil if the element isn't allowed
+	term   XsdElement
+	counts []counterAction
+}
+
+// counterAction counts a repeat or resets the counter
+type counterAction struct {
+	counter   int
+	increment bool
+}
+
+// maxTransitions is the most element names a state keeps the transitions of, the names allowed by a wildcard
+// aren't all kept
+const maxTransitions = 1000
+
+// compileContent builds the automaton of a content model
+func (xsd *XSD) compileContent(cm *ContentModel) *automaton {
+	a := &automaton{xsd: xsd, terms: cm.Terms(), dfas: make(map[string]*dfaState)}
+	if all := topAll(cm.Particles); all != nil {
+		a.all = all
+		return a
+	}
+	start := a.newState()
+	end := a.sequence(cm.Particles, start, make(map[*Group]bool))
+	a.final = end
+	a.start = a.dfa(a.closure([]int{start}))
+	return a
+}
+
+// topAll is the all group that is the whole content model, directly or through a group reference
+func topAll(particles []XsdElement) *All {
+	if len(particles) != 1 {
+		return nil
+	}
+	switch t := particles[0].(type) {
+	case *All:
+		return t
+	case *Group:
+		if def := t.RefGroup; t.Ref > "" && def != nil && def.All != nil {
+			return def.All
+		}
+	}
+	return nil
+}
+
+func (a *automaton) newState() int {
+	a.states = append(a.states, &nfaState{next: -1})
+	return len(a.states) - 1
+}
+
+func (a *automaton) epsilon(from, to int) {
+	a.states[from].epsilon = append(a.states[from].epsilon, to)
+}
+
+// sequence adds the particles one after the other from the state, returning the state at the end
+func (a *automaton) sequence(particles []XsdElement, from int, expanding map[*Group]bool) int {
+	for _, p := range particles {
+		from = a.particle(p, from, expanding)
+	}
+	return from
+}
+
+// particle adds the particle repeated between minOccurs and maxOccurs times
+func (a *automaton) particle(p XsdElement, from int, expanding map[*Group]bool) int {
+	minimum, maximum := particleOccurs(p)
+	end := from
+	for i := 0; i < minimum; i++ {
+		end = a.once(p, end, expanding)
+	}
+	switch {
+	case maximum == unbounded || maximum-minimum > repeatLimit: // p*, counted if it's bounded
+		loop := a.newState()
+		a.epsilon(end, loop)
+		after := a.once(p, loop, expanding)
+		a.epsilon(after, loop)
+		if maximum != unbounded {
+			c := &counter{max: maximum - minimum, starts: make(map[int]bool), inside: make(map[int]bool)}
+			for _, s := range a.closure([]int{loop}) {
+				if a.states[s].term != nil {
+					c.starts[s] = true
+				}
+			}
+			for s := loop; s < len(a.states); s++ {
+				if a.states[s].term != nil {
+					c.inside[s] = true
+				}
+			}
+			a.counters = append(a.counters, c)
+		}
+		end = a.newState()
+		a.epsilon(loop, end)
+	default: // (p(p)?)?
+		for i := minimum; i < maximum; i++ {
+			skip := a.newState()
+			a.epsilon(end, skip)
+			a.epsilon(a.once(p, end, expanding), skip)
+			end = skip
+		}
+	}
+	return end
+}
+
+// once adds one occurrence of the particle
+func (a *automaton) once(p XsdElement, from int, expanding map[*Group]bool) int {
+	switch t := p.(type) {
+	case *Element, *Any:
+		if a.states[from].term != nil { // A state has one transition
+			start := a.newState()
+			a.epsilon(from, start)
+			from = start
+		}
+		to := a.newState()
+		a.states[from].term, a.states[from].next = t, to
+		return to
+	case *Sequence:
+		return a.sequence(t.Particles(), from, expanding)
+	case *Choice:
+		end := a.newState()
+		for _, cp := range t.Particles() {
+			start := a.newState()
+			a.epsilon(from, start)
+			a.epsilon(a.particle(cp, start, expanding), end)
+		}
+		return end
+	case *All: // An all inside another content model (XSD 1.1) is any of its elements in any order, the counts aren't checked
+		loop := a.newState()
+		a.epsilon(from, loop)
+		for _, e := range t.Elements {
+			start := a.newState()
+			a.epsilon(loop, start)
+			a.epsilon(a.once(e, start, expanding), loop)
+		}
+		return loop
+	case *Group:
+		def := t
+		if t.Ref > "" {
+			def = t.RefGroup
+		}
+		if def == nil || expanding[def] {
+			return from
+		}
+		expanding[def] = true
+		defer delete(expanding, def)
+		return a.sequence(contentParticles(def.Sequence, def.Choice, def.All, nil), from, expanding)
+	}
+	return from
+}
+
+// closure adds the states reached without an element
+func (a *automaton) closure(states []int) (closed []int) {
+	seen := make(map[int]bool)
+	var add func(s int)
+	add = func(s int) {
+		if seen[s] {
+			return
+		}
+		seen[s] = true
+		closed = append(closed, s)
+		for _, e := range a.states[s].epsilon {
+			add(e)
+		}
+	}
+	for _, s := range states {
+		add(s)
+	}
+	return
+}
+
+// dfa is the deterministic state of a set of nfa states, there's one for each set
+func (a *automaton) dfa(states []int) *dfaState {
+	sort.Ints(states)
+	var key strings.Builder
+	for _, s := range states {
+		key.WriteString(strconv.Itoa(s))
+		key.WriteByte(',')
+	}
+	if d, found := a.dfas[key.String()]; found {
+		return d
+	}
+	d := &dfaState{states: states, next: make(map[QName]*dfaTransition)}
+	a.dfas[key.String()] = d
+	for _, s := range states {
+		if s == a.final {
+			d.final = true
+		}
+	}
+	return d
+}
+
+// step follows the transition for a child element, the term is the particle the element matches.
+// to is nil if the element isn't allowed in this state or a counted particle would repeat too often,
+// the counts are those of the counters and are updated
+func (a *automaton) step(d *dfaState, counts []int, qn QName) (to *dfaState, term XsdElement) {
+	t, found := d.next[qn]
+	if !found {
+		t = a.transition(d, qn)
+		if len(d.next) < maxTransitions {
+			d.next[qn] = t
+		}
+	}
+	for _, action := range t.counts {
+		if action.increment && counts[action.counter] >= a.counters[action.counter].max {
+			return nil, nil
+		}
+	}
+	for _, action := range t.counts {
+		if action.increment {
+			counts[action.counter]++
+		} else {
+			counts[action.counter] = 0
+		}
+	}
+	return t.to, t.term
+}
+
+func (a *automaton) transition(d *dfaState, qn QName) *dfaTransition {
+	t := &dfaTransition{}
+	var taken, next []int
+	for _, s := range d.states {
+		state := a.states[s]
+		if state.term == nil || !a.matches(state.term, qn) {
+			continue
+		}
+		if t.term == nil {
+			t.term = state.term
+		}
+		taken, next = append(taken, s), append(next, state.next)
+	}
+	if len(next) == 0 {
+		return t
+	}
+	t.to = a.dfa(a.closure(next))
+	for i, c := range a.counters {
+		start, inside := false, false
+		for _, s := range taken {
+			start, inside = start || c.starts[s], inside || c.inside[s]
+		}
+		switch {
+		case start:
+			t.counts = append(t.counts, counterAction{counter: i, increment: true})
+		case !inside:
+			t.counts = append(t.counts, counterAction{counter: i})
+		}
+	}
+	return t
+}
+
+func (a *automaton) matches(term XsdElement, qn QName) bool {
+	switch t := term.(type) {
+	case *Element:
+		return a.xsd.matchesElement(t, qn)
+	case *Any:
+		return a.xsd.wildcardAllows(t, qn.Namespace)
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package xsd
+
+import (
+	"fmt"
+)
+
+// BuiltinType is one of the datatypes built in to XML Schema, https://www.w3.org/TR/xmlschema11-2/#built-in-datatypes
+type BuiltinType struct {
+	Name       string // Local name in the XML Schema namespace
+	Base       string // The type it's derived from by restriction, blank for anyType
+	Primitive  bool   // A primitive type is derived from anySimpleType (anyAtomicType in XSD 1.1) without any facets
+	ItemType   string // The item type of a built in list type, e.g. IDREFS is a list of IDREF
+	WhiteSpace string // preserve, replace or collapse
+	// The implicit facets of the type
+	MinInclusive   string
+	MaxInclusive   string
+	MinLength      string
+	FractionDigits string
+	Pattern        string
+	XSD11          bool // New in XSD 1.1
+}
+
+// builtinTypes is the registry of built in types keyed by the local name
+var builtinTypes = func() map[string]*BuiltinType {
+	types := make(map[string]*BuiltinType)
+	for _, bt := range []*BuiltinType{
+		{Name: "anyType"},
+		{Name: "anySimpleType", Base: "anyType"},
+		{Name: "anyAtomicType", Base: "anySimpleType", XSD11: true},
+
+		// Primitive types
+		{Name: "string", Base: "anyAtomicType", Primitive: true, WhiteSpace: "preserve"},
+		{Name: "boolean", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "decimal", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "float", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "double", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "duration", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "dateTime", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "time", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "date", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "gYearMonth", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "gYear", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "gMonthDay", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "gDay", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "gMonth", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "hexBinary", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "base64Binary", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "anyURI", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "QName", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+		{Name: "NOTATION", Base: "anyAtomicType", Primitive: true, WhiteSpace: "collapse"},
+
+		// Derived from string
+		{Name: "normalizedString", Base: "string", WhiteSpace: "replace"},
+		{Name: "token", Base: "normalizedString", WhiteSpace: "collapse"},
+		{Name: "language", Base: "token", WhiteSpace: "collapse", Pattern: `[a-zA-Z]{1,8}(-[a-zA-Z0-9]{1,8})*`},
+		{Name: "NMTOKEN", Base: "token", WhiteSpace: "collapse", Pattern: `\c+`},
+		{Name: "NMTOKENS", Base: "anySimpleType", ItemType: "NMTOKEN", WhiteSpace: "collapse", MinLength: "1"},
+		{Name: "Name", Base: "token", WhiteSpace: "collapse", Pattern: `\i\c*`},
+		{Name: "NCName", Base: "Name", WhiteSpace: "collapse", Pattern: `[\i-[:]][\c-[:]]*`},
+		{Name: "ID", Base: "NCName", WhiteSpace: "collapse"},
+		{Name: "IDREF", Base: "NCName", WhiteSpace: "collapse"},
+		{Name: "IDREFS", Base: "anySimpleType", ItemType: "IDREF", WhiteSpace: "collapse", MinLength: "1"},
+		{Name: "ENTITY", Base: "NCName", WhiteSpace: "collapse"},
+		{Name: "ENTITIES", Base: "anySimpleType", ItemType: "ENTITY", WhiteSpace: "collapse", MinLength: "1"},
+
+		// Derived from decimal
+		{Name: "integer", Base: "decimal", WhiteSpace: "collapse", FractionDigits: "0", Pattern: `[\-+]?[0-9]+`},
+		{Name: "nonPositiveInteger", Base: "integer", WhiteSpace: "collapse", FractionDigits: "0", MaxInclusive: "0"},
+		{Name: "negativeInteger", Base: "nonPositiveInteger", WhiteSpace: "collapse", FractionDigits: "0", MaxInclusive: "-1"},
+		{Name: "long", Base: "integer", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "-9223372036854775808", MaxInclusive: "9223372036854775807"},
+		{Name: "int", Base: "long", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "-2147483648", MaxInclusive: "2147483647"},
+		{Name: "short", Base: "int", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "-32768", MaxInclusive: "32767"},
+		{Name: "byte", Base: "short", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "-128", MaxInclusive: "127"},
+		{Name: "nonNegativeInteger", Base: "integer", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "0"},
+		{Name: "unsignedLong", Base: "nonNegativeInteger", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "0", MaxInclusive: "18446744073709551615"},
+		{Name: "unsignedInt", Base: "unsignedLong", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "0", MaxInclusive: "4294967295"},
+		{Name: "unsignedShort", Base: "unsignedInt", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "0", MaxInclusive: "65535"},
+		{Name: "unsignedByte", Base: "unsignedShort", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "0", MaxInclusive: "255"},
+		{Name: "positiveInteger", Base: "nonNegativeInteger", WhiteSpace: "collapse", FractionDigits: "0", MinInclusive: "1"},
+
+		// Derived from duration and dateTime in XSD 1.1
+		{Name: "yearMonthDuration", Base: "duration", WhiteSpace: "collapse", Pattern: `[^DT]*`, XSD11: true},
+		{Name: "dayTimeDuration", Base: "duration", WhiteSpace: "collapse", Pattern: `[^YM]*(T.*)?`, XSD11: true},
+		{Name: "dateTimeStamp", Base: "dateTime", WhiteSpace: "collapse", XSD11: true},
+	} {
+		types[bt.Name] = bt
+	}
+	return types
+}()
+
+// LookupBuiltinType finds the built in type with the local name, e.g. "positiveInteger"
+func LookupBuiltinType(name string) (bt *BuiltinType, found bool) {
+	bt, found = builtinTypes[name]
+	return
+}
+
+// BaseType is the type this type is derived from, nil for anyType
+func (bt *BuiltinType) BaseType() *BuiltinType {
+	return builtinTypes[bt.Base]
+}
+
+// IsList is true for the built in list types NMTOKENS, IDREFS and ENTITIES
+func (bt *BuiltinType) IsList() bool {
+	return bt.ItemType > ""
+}
+
+// PrimitiveType is the primitive type this type is derived from, itself if it's primitive.
+// nil for the ur-types and the built in lists, which don't have one
+func (bt *BuiltinType) PrimitiveType() *BuiltinType {
+	for t := bt; t != nil; t = t.BaseType() {
+		if t.Primitive {
+			return t
+		}
+	}
+	return nil
+}
+
+// DerivesFrom is true if the type is the named type or is derived from it,
+// e.g. positiveInteger derives from nonNegativeInteger, integer, decimal, anyAtomicType, anySimpleType and anyType
+func (bt *BuiltinType) DerivesFrom(name string) bool {
+	for t := bt; t != nil; t = t.BaseType() {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// PrimitiveType finds the primitive built in type a simple type is derived from by following the restriction bases.
+// List types don't have a primitive type, a union only has one if all its members have the same primitive type
+func (xsd *XSD) PrimitiveType(st *SimpleType) (bt *BuiltinType, err error) {
+	return xsd.primitiveType(st, make(map[*SimpleType]bool))
+}
+
+func (xsd *XSD) primitiveType(st *SimpleType, deriving map[*SimpleType]bool) (bt *BuiltinType, err error) {
+	if st == nil {
+		return nil, fmt.Errorf("no simple type")
+	}
+	if deriving[st] {
+		return nil, fmt.Errorf("simpleType %s is %w", st.Name, ErrDerivedFromItself)
+	}
+	deriving[st] = true
+	defer delete(deriving, st)
+	switch {
+	case st.Restriction != nil:
+		if st.Restriction.Base == "" {
+			return xsd.primitiveType(st.Restriction.SimpleType, deriving)
+		}
+		return xsd.primitiveTypeOf(st.Restriction.BaseQName, deriving)
+	case st.List != nil:
+		return nil, fmt.Errorf("simpleType %s is a list so doesn't have a primitive type", st.Name)
+	case st.Union != nil:
+		var members []*BuiltinType
+		for _, qn := range st.Union.MemberTypeQNames {
+			var member *BuiltinType
+			if member, err = xsd.primitiveTypeOf(qn, deriving); err != nil {
+				return nil, err
+			}
+			members = append(members, member)
+		}
+		for _, memberType := range st.Union.SimpleTypes {
+			var member *BuiltinType
+			if member, err = xsd.primitiveType(memberType, deriving); err != nil {
+				return nil, err
+			}
+			members = append(members, member)
+		}
+		for _, member := range members {
+			if bt != nil && bt != member {
+				return nil, fmt.Errorf("simpleType %s is a union of %s and %s so doesn't have a primitive type", st.Name, bt.Name, member.Name)
+			}
+			bt = member
+		}
+		if bt == nil {
+			return nil, fmt.Errorf("simpleType %s is a union without members", st.Name)
+		}
+		return
+	}
+	return nil, fmt.Errorf("simpleType %s has no restriction, list or union", st.Name)
+}
+
+// primitiveTypeOf finds the primitive type of a named built in or simple type
+func (xsd *XSD) primitiveTypeOf(qn QName, deriving map[*SimpleType]bool) (bt *BuiltinType, err error) {
+	if qn.Namespace == XMLSchemaNamespace {
+		builtin, found := LookupBuiltinType(qn.Local)
+		if !found {
+			return nil, fmt.Errorf("%s is not a built in type", qn)
+		}
+		if bt = builtin.PrimitiveType(); bt == nil {
+			return nil, fmt.Errorf("%s doesn't have a primitive type", qn)
+		}
+		return
+	}
+	xe, found := xsd.LookupType(qn)
+	if !found {
+		return nil, fmt.Errorf("could not find type %s", qn)
+	}
+	st, isSimple := xe.(*SimpleType)
+	if !isSimple {
+		return nil, fmt.Errorf("%s is not a simple type", qn)
+	}
+	return xsd.primitiveType(st, deriving)
+}
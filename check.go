@@ -0,0 +1,570 @@
+package xsd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic is a problem with the schema, one of the XML Schema component constraints it breaks
+type Diagnostic struct {
+	Component  XsdElement // The component with the problem
+	Constraint string     // The name of the constraint in the XML Schema recommendation, e.g. cos-nonambig
+	Message    string
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s in %s", d.Constraint, d.Message, d.Component.ToString())
+}
+
+// unbounded is the maximum occurs of maxOccurs="unbounded"
+const unbounded = -1
+
+// Check looks for the mistakes encoding/xml doesn't notice, such as duplicate global names, an element with
+// both name and ref, maxOccurs less than minOccurs, facets that don't go together or don't apply to the type,
+// derivations the base type doesn't allow and content models breaking Unique Particle Attribution.
+// Check is for a resolved schema, dangling references are reported by Resolve
+func (xsd *XSD) Check() (diagnostics []*Diagnostic) {
+	report := func(xe XsdElement, constraint, format string, a ...interface{}) {
+		diagnostics = append(diagnostics, &Diagnostic{Component: xe, Constraint: constraint, Message: fmt.Sprintf(format, a...)})
+	}
+	xsd.checkGlobalNames(report)
+	globals := make(map[*Element]bool)
+	for _, e := range xsd.Elements {
+		globals[e] = true
+	}
+	_ = xsd.ApplyFunction(func(xe XsdElement) error {
+		switch t := xe.(type) {
+		case *Element:
+			checkElement(t, globals[t], report)
+			checkOccurs(t, t.MinOccurs, t.MaxOccurs, report)
+		case *Attribute:
+			checkAttribute(t, report)
+		case *Any:
+			checkOccurs(t, t.MinOccurs, t.MaxOccurs, report)
+		case *Group:
+			checkOccurs(t, t.MinOccurs, t.MaxOccurs, report)
+		case *All:
+			checkOccurs(t, t.MinOccurs, t.MaxOccurs, report)
+			xsd.checkAll(t, report)
+		case *Sequence:
+			checkOccurs(t, t.MinOccurs, t.MaxOccurs, report)
+		case *Choice:
+			checkOccurs(t, t.MinOccurs, t.MaxOccurs, report)
+		case *ComplexType:
+			xsd.checkComplexDerivation(t, report)
+			xsd.checkDeterministic(t, report)
+			xsd.checkElementsConsistent(t, report)
+		case *SimpleType:
+			xsd.checkSimpleDerivation(t, report)
+		}
+		return nil
+	})
+	return
+}
+
+type reportFunc func(xe XsdElement, constraint, format string, a ...interface{})
+
+// checkGlobalNames reports global components with the same name, each kind of component has its own symbol space
+func (xsd *XSD) checkGlobalNames(report reportFunc) {
+	names := make(map[string]map[QName]bool)
+	add := func(xe XsdElement, space string, namespace, name string) {
+		if names[space] == nil {
+			names[space] = make(map[QName]bool)
+		}
+		qn := QName{Namespace: namespace, Local: name}
+		if names[space][qn] {
+			report(xe, "sch-props-correct.2", "%s %s is declared more than once", space, qn)
+		}
+		names[space][qn] = true
+	}
+	for _, st := range xsd.SimpleTypes {
+		add(st, "type", st.Namespace, st.Name)
+	}
+	for _, ct := range xsd.ComplexTypes {
+		add(ct, "type", ct.Namespace, ct.Name)
+	}
+	for _, e := range xsd.Elements {
+		add(e, "element", e.Namespace, e.Name)
+	}
+	for _, a := range xsd.Attributes {
+		add(a, "attribute", a.Namespace, a.Name)
+	}
+	for _, g := range xsd.Groups {
+		add(g, "group", g.Namespace, g.Name)
+	}
+	for _, ag := range xsd.AttributeGroups {
+		add(ag, "attributeGroup", ag.Namespace, ag.Name)
+	}
+	_ = xsd.ApplyFunction(func(xe XsdElement) error {
+		switch t := xe.(type) {
+		case *Unique:
+			add(t, "identity constraint", t.Namespace, t.Name)
+		case *Key:
+			add(t, "identity constraint", t.Namespace, t.Name)
+		case *KeyRef:
+			add(t, "identity constraint", t.Namespace, t.Name)
+		}
+		return nil
+	})
+}
+
+func checkElement(e *Element, global bool, report reportFunc) {
+	switch {
+	case e.Name > "" && e.Ref > "":
+		report(e, "src-element.2.1", "an element can't have both a name and a ref")
+	case e.Name == "" && e.Ref == "":
+		report(e, "src-element.2.1", "an element needs a name or a ref")
+	case e.Ref > "" && (e.Type > "" || e.ComplexType != nil || e.SimpleType != nil || e.Nillable || e.Default > "" || e.Fixed > "" || e.Block > ""):
+		report(e, "src-element.2.2", "a reference to element %s can only have minOccurs, maxOccurs and an annotation", e.Ref)
+	}
+	if e.Default > "" && e.Fixed > "" {
+		report(e, "src-element.1", "element %s can't have both a default and a fixed value", e.Name)
+	}
+	if (e.Type > "" && (e.ComplexType != nil || e.SimpleType != nil)) || (e.ComplexType != nil && e.SimpleType != nil) {
+		report(e, "src-element.3", "element %s can only have one of a type, a complexType and a simpleType", e.Name)
+	}
+	if global && (e.Ref > "" || e.MinOccurs > "" || e.MaxOccurs > "") {
+		report(e, "s4s-att-not-allowed", "global element %s can't have ref, minOccurs or maxOccurs", e.Name)
+	}
+}
+
+func checkAttribute(a *Attribute, report reportFunc) {
+	switch {
+	case a.Name > "" && a.Ref > "":
+		report(a, "src-attribute.3.1", "an attribute can't have both a name and a ref")
+	case a.Name == "" && a.Ref == "":
+		report(a, "src-attribute.3.1", "an attribute needs a name or a ref")
+	}
+	if a.Default > "" && a.Fixed > "" {
+		report(a, "src-attribute.1", "attribute %s can't have both a default and a fixed value", a.Name)
+	}
+	if a.Default > "" && a.Use > "" && a.Use != "optional" {
+		report(a, "src-attribute.2", "attribute %s has a default so its use has to be optional, not %s", a.Name, a.Use)
+	}
+	if a.Type > "" && a.SimpleType != nil {
+		report(a, "src-attribute.4", "attribute %s can't have both a type and a simpleType", a.Name)
+	}
+}
+
+// checkOccurs reports minOccurs and maxOccurs that aren't numbers or are the wrong way round
+func checkOccurs(xe XsdElement, minOccurs, maxOccurs string, report reportFunc) {
+	minimum, maximum, err := occursRange(minOccurs, maxOccurs)
+	if err != nil {
+		report(xe, "s4s-att-invalid-value", "%v", err)
+		return
+	}
+	if maximum != unbounded && maximum < minimum {
+		report(xe, "p-props-correct.2.1", "maxOccurs %d is less than minOccurs %d", maximum, minimum)
+	}
+}
+
+// occursRange converts minOccurs and maxOccurs, both default to 1. maximum is unbounded (-1) for maxOccurs="unbounded"
+func occursRange(minOccurs, maxOccurs string) (minimum, maximum int, err error) {
+	minimum, maximum = 1, 1
+	if minOccurs > "" {
+		if minimum, err = strconv.Atoi(minOccurs); err != nil || minimum < 0 {
+			return 1, 1, fmt.Errorf("minOccurs %q is not a non negative integer", minOccurs)
+		}
+	}
+	if maxOccurs == "unbounded" {
+		return minimum, unbounded, nil
+	}
+	if maxOccurs > "" {
+		if maximum, err = strconv.Atoi(maxOccurs); err != nil || maximum < 0 {
+			return minimum, 1, fmt.Errorf("maxOccurs %q is not a non negative integer or unbounded", maxOccurs)
+		}
+	}
+	return
+}
+
+// particleOccurs is the range of times a particle can appear, invalid values are taken as 1, see checkOccurs
+func particleOccurs(p XsdElement) (minimum, maximum int) {
+	var minOccurs, maxOccurs string
+	switch t := p.(type) {
+	case *Element:
+		minOccurs, maxOccurs = t.MinOccurs, t.MaxOccurs
+	case *Any:
+		minOccurs, maxOccurs = t.MinOccurs, t.MaxOccurs
+	case *Sequence:
+		minOccurs, maxOccurs = t.MinOccurs, t.MaxOccurs
+	case *Choice:
+		minOccurs, maxOccurs = t.MinOccurs, t.MaxOccurs
+	case *All:
+		minOccurs, maxOccurs = t.MinOccurs, t.MaxOccurs
+	case *Group:
+		minOccurs, maxOccurs = t.MinOccurs, t.MaxOccurs
+	}
+	minimum, maximum, _ = occursRange(minOccurs, maxOccurs)
+	return
+}
+
+// Unique Particle Attribution (cos-nonambig): an element in a document must match only one particle
+// without looking ahead. The content model is compiled and each state it can reach is checked for
+// different particles matching the same element. These are the XSD 1.0 rules so an element competing
+// with a wildcard is reported
+
+// checkDeterministic reports the particles of a complex type's content model that compete for an element
+func (xsd *XSD) checkDeterministic(ct *ComplexType, report reportFunc) {
+	cm, err := xsd.EffectiveContent(ct)
+	if err != nil || cm.SimpleType != (QName{}) {
+		return // Reported with the derivation
+	}
+	a := xsd.compileContent(cm)
+	if a.all != nil {
+		return // checkAll
+	}
+	reported := make(map[[2]XsdElement]bool)
+	seen := map[*dfaState]bool{a.start: true}
+	for states := []*dfaState{a.start}; len(states) > 0; states = states[1:] {
+		var terms []XsdElement
+		next := make(map[XsdElement][]int)
+		for _, s := range states[0].states {
+			state := a.states[s]
+			if state.term == nil {
+				continue
+			}
+			if _, found := next[state.term]; !found {
+				terms = append(terms, state.term)
+			}
+			next[state.term] = append(next[state.term], state.next)
+		}
+		for i, term := range terms {
+			for _, other := range terms[i+1:] {
+				if element, found := xsd.overlap(xsd.terms(term), xsd.terms(other)); found && !reported[[2]XsdElement{term, other}] {
+					reported[[2]XsdElement{term, other}] = true
+					report(ct, "cos-nonambig", "%s can match %s or %s", termName(element), term.ToString(), other.ToString())
+				}
+			}
+			if to := a.dfa(a.closure(next[term])); !seen[to] {
+				seen[to] = true
+				states = append(states, to)
+			}
+		}
+	}
+}
+
+// checkAll reports elements of an all that have the same name
+func (xsd *XSD) checkAll(al *All, report reportFunc) {
+	for i, e := range al.Elements {
+		for _, other := range al.Elements[i+1:] {
+			if term, found := xsd.overlap(xsd.terms(e), xsd.terms(other)); found {
+				report(al, "cos-nonambig", "%s can match more than one element of the all", termName(term))
+			}
+		}
+	}
+}
+
+// terms are the elements a *Element or *Any particle matches, an element's substitution group members can appear in its place
+func (xsd *XSD) terms(term XsdElement) (terms []XsdElement) {
+	terms = append(terms, term)
+	if e, isElement := term.(*Element); isElement {
+		for _, m := range xsd.SubstitutionGroupMembers(declaration(e)) {
+			terms = append(terms, m)
+		}
+	}
+	return
+}
+
+// overlap finds a term of the first list that matches the same element as a term of the second
+func (xsd *XSD) overlap(terms, others []XsdElement) (XsdElement, bool) {
+	for _, term := range terms {
+		for _, other := range others {
+			e, isElement := term.(*Element)
+			otherElement, otherIsElement := other.(*Element)
+			switch {
+			case isElement && otherIsElement:
+				if elementQName(e) == elementQName(otherElement) {
+					return term, true
+				}
+			case isElement:
+				if xsd.wildcardAllows(other.(*Any), elementQName(e).Namespace) {
+					return term, true
+				}
+			case otherIsElement:
+				if xsd.wildcardAllows(term.(*Any), elementQName(otherElement).Namespace) {
+					return other, true
+				}
+			default:
+				if xsd.wildcardsIntersect(term.(*Any), other.(*Any)) {
+					return term, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+// elementQName is the name of an element, the referenced element's name for a reference
+func elementQName(e *Element) QName {
+	if e.Ref > "" {
+		return e.RefQName
+	}
+	return QName{Namespace: e.Namespace, Local: e.Name}
+}
+
+func termName(xe XsdElement) string {
+	if e, isElement := xe.(*Element); isElement {
+		return fmt.Sprintf("element %s", elementQName(e))
+	}
+	return "a wildcard element"
+}
+
+// wildcardNamespaces are the namespaces of a wildcard's list, ##targetNamespace and ##local replaced.
+// any is true for ##any and other is true for ##other
+func (xsd *XSD) wildcardNamespaces(a *Any) (namespaces map[string]bool, any bool, other bool) {
+	switch strings.TrimSpace(a.Namespace) {
+	case "", "##any":
+		return nil, true, false
+	case "##other":
+		return nil, false, true
+	}
+	namespaces = make(map[string]bool)
+	for _, ns := range strings.Fields(a.Namespace) {
+		switch ns {
+		case "##targetNamespace":
+			namespaces[xsd.TargetNamespace] = true
+		case "##local":
+			namespaces[""] = true
+		default:
+			namespaces[ns] = true
+		}
+	}
+	return
+}
+
+// wildcardAllows is true if an element in the namespace matches the wildcard
+func (xsd *XSD) wildcardAllows(a *Any, namespace string) bool {
+	namespaces, any, other := xsd.wildcardNamespaces(a)
+	switch {
+	case any:
+		return true
+	case other:
+		return namespace != "" && namespace != xsd.TargetNamespace
+	}
+	return namespaces[namespace]
+}
+
+// wildcardsIntersect is true if an element could match both wildcards
+func (xsd *XSD) wildcardsIntersect(a, b *Any) bool {
+	aNamespaces, aAny, aOther := xsd.wildcardNamespaces(a)
+	bNamespaces, bAny, bOther := xsd.wildcardNamespaces(b)
+	// otherAllows is true if ##other allows any of the namespaces
+	otherAllows := func(namespaces map[string]bool) bool {
+		for ns := range namespaces {
+			if ns != "" && ns != xsd.TargetNamespace {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case aAny || bAny || (aOther && bOther):
+		return true
+	case aOther:
+		return otherAllows(bNamespaces)
+	case bOther:
+		return otherAllows(aNamespaces)
+	}
+	for ns := range aNamespaces {
+		if bNamespaces[ns] {
+			return true
+		}
+	}
+	return false
+}
+
+// checkElementsConsistent reports elements with the same name but different types in a content model (cos-element-consistent)
+func (xsd *XSD) checkElementsConsistent(ct *ComplexType, report reportFunc) {
+	cm, err := xsd.EffectiveContent(ct)
+	if err != nil {
+		return // A broken derivation is reported by checkComplexDerivation
+	}
+	types := make(map[QName]QName)
+	for _, e := range cm.elements() {
+		if e.Ref > "" || e.Type == "" {
+			continue
+		}
+		qn := elementQName(e)
+		if typeQName, found := types[qn]; found && typeQName != e.TypeQName {
+			report(ct, "cos-element-consistent", "element %s has types %s and %s", qn, typeQName, e.TypeQName)
+			continue
+		}
+		types[qn] = e.TypeQName
+	}
+}
+
+// finalBlocks is true if a final attribute, or the schema's finalDefault, doesn't allow the derivation method
+func (xsd *XSD) finalBlocks(final, method string) bool {
+	if final == "" {
+		final = xsd.FinalDefault
+	}
+	for _, f := range strings.Fields(final) {
+		if f == "#all" || f == method {
+			return true
+		}
+	}
+	return false
+}
+
+// checkComplexDerivation reports derivations the base type doesn't allow, extending complex content from a simple type
+// and circular derivations
+func (xsd *XSD) checkComplexDerivation(ct *ComplexType, report reportFunc) {
+	check := func(base QName, method string, complexContent bool) {
+		switch t := xsd.symbolTable().types[base].(type) {
+		case *ComplexType:
+			if xsd.finalBlocks(t.Final, method) {
+				constraint := "derivation-ok-restriction.1"
+				if method == "extension" {
+					constraint = "cos-ct-extends.1.1"
+				}
+				report(ct, constraint, "complexType %s doesn't allow derivation by %s", base, method)
+			}
+		case *SimpleType:
+			if complexContent {
+				report(ct, "src-ct.1", "complexContent can't be derived from simpleType %s", base)
+			}
+		default:
+			if bt, builtin := LookupBuiltinType(base.Local); complexContent && builtin && base.Namespace == XMLSchemaNamespace && bt.Name != "anyType" {
+				report(ct, "src-ct.1", "complexContent can't be derived from the built in simple type %s", base)
+			}
+		}
+	}
+	if ex := ct.ComplexContent.extension(); ex != nil {
+		check(ex.BaseQName, "extension", true)
+	}
+	if r := ct.ComplexContent.restriction(); r != nil {
+		check(r.BaseQName, "restriction", true)
+	}
+	if ex := ct.SimpleContent.extension(); ex != nil {
+		check(ex.BaseQName, "extension", false)
+	}
+	if r := ct.SimpleContent.restriction(); r != nil {
+		check(r.BaseQName, "restriction", false)
+		base := r.BaseQName
+		if cm, err := xsd.EffectiveContent(ct); err == nil && cm.SimpleType.Local > "" {
+			base = cm.SimpleType // The facets restrict the content of a complex base type
+		}
+		xsd.checkFacets(r, base, report)
+	}
+	if ct.Name > "" && (ct.ComplexContent != nil || ct.SimpleContent != nil) {
+		if _, err := xsd.EffectiveContent(ct); errors.Is(err, ErrDerivedFromItself) {
+			report(ct, "ct-props-correct.3", "complexType %s is derived from itself", ct.Name)
+		}
+	}
+}
+
+// checkSimpleDerivation reports restrictions the base type doesn't allow, circular derivations and facet problems
+func (xsd *XSD) checkSimpleDerivation(st *SimpleType, report reportFunc) {
+	if st.Restriction == nil {
+		return
+	}
+	if base, isSimple := xsd.symbolTable().types[st.Restriction.BaseQName].(*SimpleType); isSimple && xsd.finalBlocks(base.Final, "restriction") {
+		report(st, "st-props-correct.3", "simpleType %s doesn't allow derivation by restriction", st.Restriction.BaseQName)
+	}
+	if _, err := xsd.PrimitiveType(st); errors.Is(err, ErrDerivedFromItself) {
+		report(st, "st-props-correct.2", "simpleType %s is derived from itself", st.Name)
+		return
+	}
+	xsd.checkFacets(st.Restriction, st.Restriction.BaseQName, report)
+}
+
+// checkFacets reports facets that contradict each other or don't apply to the primitive type of the base
+func (xsd *XSD) checkFacets(r *Restriction, base QName, report reportFunc) {
+	if r.Length != nil && (r.MinLength != nil || r.MaxLength != nil) {
+		report(r, "length-minLength-maxLength", "length can't be used with minLength or maxLength")
+	}
+	if r.MinLength != nil && r.MaxLength != nil {
+		minLength, minErr := strconv.Atoi(r.MinLength.Value)
+		maxLength, maxErr := strconv.Atoi(r.MaxLength.Value)
+		if minErr == nil && maxErr == nil && minLength > maxLength {
+			report(r, "minLength-less-than-equal-to-maxLength", "minLength %d is more than maxLength %d", minLength, maxLength)
+		}
+	}
+	if r.MinInclusive != nil && r.MinExclusive != nil {
+		report(r, "minInclusive-minExclusive", "minInclusive and minExclusive can't both be used")
+	}
+	if r.MaxInclusive != nil && r.MaxExclusive != nil {
+		report(r, "maxInclusive-maxExclusive", "maxInclusive and maxExclusive can't both be used")
+	}
+	if r.TotalDigits != nil && r.FractionDigits != nil {
+		totalDigits, totalErr := strconv.Atoi(r.TotalDigits.Value)
+		fractionDigits, fractionErr := strconv.Atoi(r.FractionDigits.Value)
+		if totalErr == nil && fractionErr == nil && fractionDigits > totalDigits {
+			report(r, "fractionDigits-totalDigits", "fractionDigits %d is more than totalDigits %d", fractionDigits, totalDigits)
+		}
+	}
+	for _, p := range r.Patterns {
+		if _, err := CompileRegexp(p.Value); err != nil {
+			report(r, "s4s-att-invalid-value", "%v", err)
+		}
+	}
+	// Bounds the wrong way round
+	primitive := xsd.primitiveOf(nil, base)
+	orderedAs := "decimal"
+	if primitive != nil {
+		orderedAs = primitive.Name
+	}
+	type bound struct{ facet, value string }
+	var lower, upper []bound
+	if r.MinInclusive != nil {
+		lower = append(lower, bound{"minInclusive", r.MinInclusive.Value})
+	}
+	if r.MinExclusive != nil {
+		lower = append(lower, bound{"minExclusive", r.MinExclusive.Value})
+	}
+	if r.MaxInclusive != nil {
+		upper = append(upper, bound{"maxInclusive", r.MaxInclusive.Value})
+	}
+	if r.MaxExclusive != nil {
+		upper = append(upper, bound{"maxExclusive", r.MaxExclusive.Value})
+	}
+	for _, l := range lower {
+		for _, u := range upper {
+			low, lowErr := ParseValue(orderedAs, l.value)
+			high, highErr := ParseValue(orderedAs, u.value)
+			if lowErr != nil || highErr != nil {
+				continue
+			}
+			if c, ordered := low.Compare(high); ordered && (c > 0 || (c == 0 && (l.facet == "minExclusive" || u.facet == "maxExclusive"))) {
+				report(r, fmt.Sprintf("%s-less-than-equal-to-%s", l.facet, u.facet), "%s %s is not less than %s %s", l.facet, l.value, u.facet, u.value)
+			}
+		}
+	}
+
+	// Facets that don't apply to the primitive type
+	if primitive == nil {
+		return // Lists, unions and complex types aren't checked
+	}
+	var lengthFacets, orderFacets, digitFacets bool
+	switch primitive.Name {
+	case "string", "anyURI", "QName", "NOTATION", "hexBinary", "base64Binary":
+		lengthFacets = true
+	case "boolean":
+	case "decimal":
+		orderFacets, digitFacets = true, true
+	default: // Numbers, dates, times and durations are ordered
+		orderFacets = true
+	}
+	inapplicable := func(present bool, facet string) {
+		if present {
+			report(r, "cos-applicable-facets", "%s doesn't apply to %s, a %s", facet, base, primitive.Name)
+		}
+	}
+	if !lengthFacets {
+		inapplicable(r.Length != nil, "length")
+		inapplicable(r.MinLength != nil, "minLength")
+		inapplicable(r.MaxLength != nil, "maxLength")
+	}
+	if !orderFacets {
+		inapplicable(r.MinInclusive != nil, "minInclusive")
+		inapplicable(r.MinExclusive != nil, "minExclusive")
+		inapplicable(r.MaxInclusive != nil, "maxInclusive")
+		inapplicable(r.MaxExclusive != nil, "maxExclusive")
+	}
+	if !digitFacets {
+		inapplicable(r.TotalDigits != nil, "totalDigits")
+		inapplicable(r.FractionDigits != nil, "fractionDigits")
+	}
+}
@@ -0,0 +1,207 @@
+package xsd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ContentModel is the effective content of a complex type once the content of its base types is included
+type ContentModel struct {
+	Type         *ComplexType
+	Particles    []XsdElement  // *Sequence, *Choice, *All or *Group, the content of the base types comes first then that of each extension
+	Attributes   []*Attribute  // The attributes of the type and its base types, with attribute groups expanded
+	AnyAttribute *AnyAttribute // The attribute wildcard nearest the type
+	Mixed        bool
+	SimpleType   QName // The simple type of the text of a type with simple content
+}
+
+// ErrDerivedFromItself is the error of a type whose derivation is a cycle
+var ErrDerivedFromItself = errors.New("derived from itself")
+
+// EffectiveContent flattens the derivation of a complex type.
+// An extension adds its particles after those of the base type and adds its attributes to those of the base type.
+// A restriction of complex content replaces the particles of the base type, restricting either kind of content
+// replaces base attributes with the same name and removes the prohibited ones
+func (xsd *XSD) EffectiveContent(ct *ComplexType) (cm *ContentModel, err error) {
+	return xsd.effectiveContent(ct, make(map[*ComplexType]bool))
+}
+
+func (xsd *XSD) effectiveContent(ct *ComplexType, deriving map[*ComplexType]bool) (cm *ContentModel, err error) {
+	if ct == nil {
+		return nil, fmt.Errorf("no complex type")
+	}
+	if deriving[ct] {
+		return nil, fmt.Errorf("complexType %s is %w", ct.Name, ErrDerivedFromItself)
+	}
+	deriving[ct] = true
+	defer delete(deriving, ct)
+
+	cm = &ContentModel{Type: ct, Mixed: ct.IsMixed(), AnyAttribute: ct.AnyAttribute}
+	var ex *Extension
+	var r *Restriction
+	var simple bool
+	switch {
+	case ct.ComplexContent != nil:
+		ex, r = ct.ComplexContent.Extension, ct.ComplexContent.Restriction
+	case ct.SimpleContent != nil:
+		ex, r, simple = ct.SimpleContent.Extension, ct.SimpleContent.Restriction, true
+	default: // Not derived from anything but xs:anyType
+		cm.Particles = contentParticles(ct.Sequence, ct.Choice, ct.All, ct.Group)
+		cm.Attributes = xsd.expandAttributes(ct.Attributes, ct.AttributeGroups)
+		return
+	}
+
+	var baseQName QName
+	switch {
+	case ex != nil:
+		baseQName = ex.BaseQName
+	case r != nil:
+		baseQName = r.BaseQName
+	default:
+		return cm, nil
+	}
+	if base, isComplex := xsd.symbolTable().types[baseQName].(*ComplexType); isComplex {
+		var baseContent *ContentModel
+		if baseContent, err = xsd.effectiveContent(base, deriving); err != nil {
+			return nil, err
+		}
+		cm.Particles, cm.Attributes, cm.SimpleType = baseContent.Particles, baseContent.Attributes, baseContent.SimpleType
+		if cm.AnyAttribute == nil {
+			cm.AnyAttribute = baseContent.AnyAttribute
+		}
+	} else if simple {
+		cm.SimpleType = baseQName // A simple type or built in type
+	}
+
+	if ex != nil {
+		cm.Particles = append(append([]XsdElement{}, cm.Particles...), contentParticles(ex.Sequence, ex.Choice, ex.All, ex.Group)...)
+		cm.Attributes = mergeAttributes(cm.Attributes, xsd.expandAttributes(ex.Attributes, ex.AttributeGroups))
+		if ex.AnyAttribute != nil {
+			cm.AnyAttribute = ex.AnyAttribute
+		}
+		return
+	}
+	if !simple {
+		cm.Particles = contentParticles(r.Sequence, r.Choice, r.All, r.Group)
+	}
+	cm.Attributes = mergeAttributes(cm.Attributes, xsd.expandAttributes(r.Attributes, r.AttributeGroups))
+	if r.AnyAttribute != nil {
+		cm.AnyAttribute = r.AnyAttribute
+	}
+	return
+}
+
+// Terms are the *Element and *Any particles of the content model in order, with groups expanded.
+// A group is expanded at each reference except inside itself
+func (cm *ContentModel) Terms() (terms []XsdElement) {
+	expanding := make(map[*Group]bool)
+	var walk func(xe XsdElement)
+	walk = func(xe XsdElement) {
+		switch t := xe.(type) {
+		case *Element, *Any:
+			terms = append(terms, t)
+		case *Sequence:
+			for _, p := range t.Particles() {
+				walk(p)
+			}
+		case *Choice:
+			for _, p := range t.Particles() {
+				walk(p)
+			}
+		case *All:
+			for _, e := range t.Elements {
+				walk(e)
+			}
+		case *Group:
+			def := t
+			if t.Ref > "" {
+				def = t.RefGroup
+			}
+			if def == nil || expanding[def] {
+				return
+			}
+			expanding[def] = true
+			defer delete(expanding, def)
+			for _, mg := range contentParticles(def.Sequence, def.Choice, def.All, nil) {
+				walk(mg)
+			}
+		}
+	}
+	for _, p := range cm.Particles {
+		walk(p)
+	}
+	return
+}
+
+// elements are the *Element terms of the content model
+func (cm *ContentModel) elements() (elements []*Element) {
+	for _, term := range cm.Terms() {
+		if e, isElement := term.(*Element); isElement {
+			elements = append(elements, e)
+		}
+	}
+	return
+}
+
+// contentParticles lists the content model particles that are present
+func contentParticles(sequence *Sequence, choice *Choice, all *All, group *Group) (particles []XsdElement) {
+	if sequence != nil {
+		particles = append(particles, sequence)
+	}
+	if choice != nil {
+		particles = append(particles, choice)
+	}
+	if all != nil {
+		particles = append(particles, all)
+	}
+	if group != nil {
+		particles = append(particles, group)
+	}
+	return
+}
+
+// expandAttributes lists the attributes with those of the referenced attribute groups
+func (xsd *XSD) expandAttributes(attributes []*Attribute, attributeGroups []*AttributeGroup) (expanded []*Attribute) {
+	expanded = append(expanded, attributes...)
+	expanding := make(map[*AttributeGroup]bool)
+	var expand func(ag *AttributeGroup)
+	expand = func(ag *AttributeGroup) {
+		def := ag
+		if ag.Ref > "" {
+			def = ag.RefAttributeGroup
+		}
+		if def == nil || expanding[def] {
+			return
+		}
+		expanding[def] = true
+		defer delete(expanding, def)
+		expanded = append(expanded, def.Attributes...)
+		for _, nested := range def.AttributeGroups {
+			expand(nested)
+		}
+	}
+	for _, ag := range attributeGroups {
+		expand(ag)
+	}
+	return
+}
+
+// mergeAttributes adds the derived attributes to the base attributes,
+// a derived attribute replaces a base attribute of the same name and a prohibited attribute removes it
+func mergeAttributes(base, derived []*Attribute) (merged []*Attribute) {
+	replaced := make(map[QName]bool)
+	for _, a := range derived {
+		replaced[a.QName()] = true
+	}
+	for _, a := range base {
+		if !replaced[a.QName()] {
+			merged = append(merged, a)
+		}
+	}
+	for _, a := range derived {
+		if a.Use != "prohibited" {
+			merged = append(merged, a)
+		}
+	}
+	return
+}
@@ -0,0 +1,170 @@
+package xsd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TypeCycle is a loop of references between global complex types and elements, e.g. a part type containing parts.
+// Recursion is legal in a schema but code generated from it has to break the loop, e.g. with a pointer
+type TypeCycle struct {
+	Components []XsdElement // The *ComplexType and *Element declarations in order, each refers to the next and the last to the first
+	Path       []QName      // The names of the components
+}
+
+// String shows the path of the cycle, e.g. complexType parttype -> element part -> complexType parttype
+func (c *TypeCycle) String() string {
+	var sSlice []string
+	for i, xe := range append(c.Components, c.Components[0]) {
+		kind := "element"
+		if _, isType := xe.(*ComplexType); isType {
+			kind = "complexType"
+		}
+		sSlice = append(sSlice, fmt.Sprintf("%s %s", kind, c.Path[i%len(c.Path)]))
+	}
+	return strings.Join(sSlice, " -> ")
+}
+
+// TypeCycles reports every cycle of references in the resolved schema. A global element refers to its type,
+// a complex type refers to the types of the elements in its content and to the global elements it references,
+// including those of its base types and groups. The content of anonymous types is part of the component declaring them
+func (xsd *XSD) TypeCycles() (cycles []*TypeCycle) {
+	var nodes []XsdElement
+	var names []QName
+	index := make(map[XsdElement]int)
+	for _, e := range xsd.Elements {
+		index[e] = len(nodes)
+		nodes, names = append(nodes, e), append(names, QName{Namespace: e.Namespace, Local: e.Name})
+	}
+	for _, ct := range xsd.ComplexTypes {
+		index[ct] = len(nodes)
+		nodes, names = append(nodes, ct), append(names, QName{Namespace: ct.Namespace, Local: ct.Name})
+	}
+	edges := make([][]int, len(nodes))
+	for i, xe := range nodes {
+		edges[i] = xsd.references(xe, index)
+	}
+
+	// Each cycle is found once, starting from the component in it that comes first
+	for start := range nodes {
+		var path []int
+		onPath := make(map[int]bool)
+		var walk func(n int)
+		walk = func(n int) {
+			path = append(path, n)
+			onPath[n] = true
+			for _, next := range edges[n] {
+				switch {
+				case next == start:
+					cycle := &TypeCycle{}
+					for _, p := range path {
+						cycle.Components = append(cycle.Components, nodes[p])
+						cycle.Path = append(cycle.Path, names[p])
+					}
+					cycles = append(cycles, cycle)
+				case next > start && !onPath[next]:
+					walk(next)
+				}
+			}
+			path = path[:len(path)-1]
+			delete(onPath, n)
+		}
+		walk(start)
+	}
+	return
+}
+
+// references lists the global components a global element or complex type refers to, by their index
+func (xsd *XSD) references(xe XsdElement, index map[XsdElement]int) (refs []int) {
+	added := make(map[int]bool)
+	add := func(ref XsdElement) {
+		if i, found := index[ref]; found && !added[i] {
+			added[i] = true
+			refs = append(refs, i)
+		}
+	}
+	seen := make(map[*ComplexType]bool)
+	var content func(ct *ComplexType)
+	var elementType func(e *Element)
+	elementType = func(e *Element) {
+		switch {
+		case e.Ref > "":
+			add(e.RefElement)
+		case e.ComplexType != nil:
+			content(e.ComplexType) // An anonymous type isn't a component of its own
+		case e.Type > "":
+			if ct, isComplex := xsd.symbolTable().types[e.TypeQName].(*ComplexType); isComplex {
+				add(ct)
+			}
+		}
+	}
+	content = func(ct *ComplexType) {
+		if seen[ct] {
+			return
+		}
+		seen[ct] = true
+		cm, err := xsd.EffectiveContent(ct)
+		if err != nil {
+			return
+		}
+		for _, e := range cm.elements() {
+			elementType(e)
+		}
+	}
+	switch t := xe.(type) {
+	case *Element:
+		elementType(t)
+	case *ComplexType:
+		content(t)
+	}
+	return
+}
+
+// markRecursive marks the message items whose type leads back to the message they are in, directly or through other messages
+func markRecursive(messages []*Message) {
+	byName := make(map[string]*Message)
+	for _, m := range messages {
+		if m.Package == "" {
+			byName[m.Name] = m
+		}
+	}
+	itemTypes := func(mi *MessageItem) (types []string) {
+		types = append(types, mi.Type)
+		for _, member := range mi.OneOf {
+			types = append(types, member.Type)
+		}
+		return
+	}
+	// reaches is true if the message can be reached from the message item types of from
+	reaches := func(from, to *Message) bool {
+		visited := make(map[*Message]bool)
+		var walk func(m *Message) bool
+		walk = func(m *Message) bool {
+			if m == to {
+				return true
+			}
+			if visited[m] {
+				return false
+			}
+			visited[m] = true
+			for _, mi := range m.MessageItems {
+				for _, t := range itemTypes(mi) {
+					if next, found := byName[t]; found && walk(next) {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		return walk(from)
+	}
+	for _, m := range messages {
+		for _, mi := range m.MessageItems {
+			for _, t := range itemTypes(mi) {
+				if next, found := byName[t]; found && reaches(next, m) {
+					mi.Recursive = true
+				}
+			}
+		}
+	}
+}
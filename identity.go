@@ -0,0 +1,250 @@
+package xsd
+
+import (
+	"strings"
+)
+
+// IdentityConstraint is a unique, key or keyref with the elements and attributes its XPaths find
+type IdentityConstraint struct {
+	Constraint XsdElement   // *Unique, *Key or *KeyRef
+	Kind       string       // unique, key or keyref
+	Name       QName        // Name of the constraint
+	Element    *Element     // The element the constraint is declared on
+	Selected   []*Element   // The elements the selector finds
+	Fields     []XsdElement // The *Element or *Attribute each field finds, nil if the field couldn't be resolved
+	Refer      XsdElement   // The *Key or *Unique a keyref refers to
+}
+
+// IdentityConstraints lists the unique, key and keyref constraints of the schema.
+// The selector and field XPaths are followed through the element declarations and types of the schema,
+// unprefixed names in an XPath match any namespace. Wildcards (*) are followed, other XPath is ignored
+func (xsd *XSD) IdentityConstraints() (constraints []*IdentityConstraint) {
+	add := func(e *Element, xe XsdElement, kind, name, namespace string, selector *Selector, fields []*Field) {
+		ic := &IdentityConstraint{Constraint: xe, Kind: kind, Name: QName{Namespace: namespace, Local: name}, Element: e}
+		if selector != nil {
+			ic.Selected = xsd.selectElements(e, selector.XPath)
+		}
+		for _, fd := range fields {
+			var found XsdElement
+			for _, selected := range ic.Selected {
+				if found = xsd.selectField(selected, fd.XPath); found != nil {
+					break
+				}
+			}
+			ic.Fields = append(ic.Fields, found)
+		}
+		constraints = append(constraints, ic)
+	}
+	// The parent of an identity constraint is the element it's declared on
+	_, _ = xsd.ApplyFunctionP(func(xe XsdElement, parent interface{}) (interface{}, error) {
+		e, _ := parent.(*Element)
+		switch t := xe.(type) {
+		case *Element:
+			return t, nil
+		case *Unique:
+			add(e, t, "unique", t.Name, t.Namespace, t.Selector, t.Fields)
+		case *Key:
+			add(e, t, "key", t.Name, t.Namespace, t.Selector, t.Fields)
+		case *KeyRef:
+			add(e, t, "keyref", t.Name, t.Namespace, t.Selector, t.Fields)
+			constraints[len(constraints)-1].Refer = t.ReferTo
+		}
+		return parent, nil
+	})
+	return
+}
+
+// selectElements follows a selector XPath from the element, alternatives are separated by |
+func (xsd *XSD) selectElements(e *Element, xpath string) (selected []*Element) {
+	for _, path := range strings.Split(xpath, "|") {
+		steps, descendants := xpathSteps(path)
+		current := []*Element{e}
+		if descendants {
+			current = append(current, xsd.descendantElements(e)...)
+		}
+		for _, step := range steps {
+			var next []*Element
+			for _, c := range current {
+				for _, child := range xsd.childElements(c) {
+					if xsd.matchesStep(child.Name, child.Namespace, child, step) {
+						next = append(next, child)
+					}
+				}
+			}
+			current = next
+		}
+		selected = append(selected, current...)
+	}
+	return
+}
+
+// selectField follows a field XPath from a selected element, the last step may be an attribute
+func (xsd *XSD) selectField(e *Element, xpath string) XsdElement {
+	for _, path := range strings.Split(xpath, "|") {
+		steps, descendants := xpathSteps(path)
+		attribute := ""
+		if len(steps) > 0 {
+			last := steps[len(steps)-1]
+			if strings.HasPrefix(last, "@") || strings.HasPrefix(last, "attribute::") {
+				attribute = strings.TrimPrefix(strings.TrimPrefix(last, "@"), "attribute::")
+				steps = steps[:len(steps)-1]
+			}
+		}
+		var elementPath string
+		if descendants {
+			elementPath = ".//"
+		}
+		elements := []*Element{e}
+		if len(steps) > 0 || descendants {
+			elements = xsd.selectElements(e, elementPath+strings.Join(steps, "/"))
+		}
+		for _, found := range elements {
+			if attribute == "" {
+				return found
+			}
+			for _, a := range xsd.elementAttributes(found) {
+				if xsd.matchesStep(a.Name, a.Namespace, a, attribute) {
+					return a
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// xpathSteps splits a restricted XPath into its steps, descendants is true if it starts with .//
+func xpathSteps(path string) (steps []string, descendants bool) {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, ".//") {
+		descendants = true
+		path = path[3:]
+	}
+	for _, step := range strings.Split(path, "/") {
+		step = strings.TrimPrefix(strings.TrimSpace(step), "child::")
+		if step == "" || step == "." {
+			continue
+		}
+		steps = append(steps, step)
+	}
+	return
+}
+
+// matchesStep is true if the element or attribute name matches a name test such as *, tns:*, name or tns:name
+func (xsd *XSD) matchesStep(name, namespace string, xe XsdElement, step string) bool {
+	switch t := xe.(type) {
+	case *Element:
+		if t.Ref > "" {
+			name, namespace = t.RefQName.Local, t.RefQName.Namespace
+		}
+	case *Attribute:
+		if t.Ref > "" {
+			name, namespace = t.RefQName.Local, t.RefQName.Namespace
+		}
+	}
+	prefix, local, hasPrefix := strings.Cut(step, ":")
+	if !hasPrefix {
+		return step == "*" || step == name
+	}
+	if ns, found := xsd.namespaceOf(prefix); !found || ns != namespace {
+		return false
+	}
+	return local == "*" || local == name
+}
+
+// declaration is the global element for an element reference
+func declaration(e *Element) *Element {
+	if e.Ref > "" && e.RefElement != nil {
+		return e.RefElement
+	}
+	return e
+}
+
+// elementType is the complex type of an element, nil if it has a simple type
+func (xsd *XSD) elementType(e *Element) *ComplexType {
+	e = declaration(e)
+	if e.ComplexType != nil {
+		return e.ComplexType
+	}
+	if e.Type > "" {
+		if ct, isComplex := xsd.symbolTable().types[e.TypeQName].(*ComplexType); isComplex {
+			return ct
+		}
+	}
+	return nil
+}
+
+// elementContent is the effective content of the element's complex type, nil if it has a simple type
+// or its type's derivation is broken
+func (xsd *XSD) elementContent(e *Element) *ContentModel {
+	ct := xsd.elementType(e)
+	if ct == nil {
+		return nil
+	}
+	cm, err := xsd.EffectiveContent(ct)
+	if err != nil {
+		return nil
+	}
+	return cm
+}
+
+// childElements are the elements that can be children of the element
+func (xsd *XSD) childElements(e *Element) (children []*Element) {
+	if cm := xsd.elementContent(e); cm != nil {
+		children = cm.elements()
+	}
+	return
+}
+
+// descendantElements are the elements that can be found anywhere below the element
+func (xsd *XSD) descendantElements(e *Element) (descendants []*Element) {
+	seen := make(map[*Element]bool)
+	var walk func(e *Element)
+	walk = func(e *Element) {
+		for _, child := range xsd.childElements(e) {
+			if seen[declaration(child)] {
+				continue
+			}
+			seen[declaration(child)] = true
+			descendants = append(descendants, child)
+			walk(child)
+		}
+	}
+	walk(e)
+	return
+}
+
+// elementAttributes are the attributes the element can have
+func (xsd *XSD) elementAttributes(e *Element) (attributes []*Attribute) {
+	if cm := xsd.elementContent(e); cm != nil {
+		attributes = cm.Attributes
+	}
+	return
+}
+
+func (cc *ComplexContent) extension() *Extension {
+	if cc == nil {
+		return nil
+	}
+	return cc.Extension
+}
+
+func (cc *ComplexContent) restriction() *Restriction {
+	if cc == nil {
+		return nil
+	}
+	return cc.Restriction
+}
+
+func (sc *SimpleContent) extension() *Extension {
+	if sc == nil {
+		return nil
+	}
+	return sc.Extension
+}
+
+func (sc *SimpleContent) restriction() *Restriction {
+	if sc == nil {
+		return nil
+	}
+	return sc.Restriction
+}
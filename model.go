@@ -4,6 +4,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type XsdElement interface {
@@ -17,11 +18,29 @@ type XsdItem interface {
 }
 
 type XSD struct {
-	XMLName      xml.Name       `xml:"schema"`
-	Import       *Import        `xml:"import,omitempty"`
-	SimpleTypes  []*SimpleType  `xml:"simpleType,omitempty"`
-	ComplexTypes []*ComplexType `xml:"complexType,omitempty"`
-	Elements     []*Element     `xml:"element,omitempty"`
+	XMLName              xml.Name            `xml:"schema"`
+	TargetNamespace      string              `xml:"targetNamespace,attr,omitempty"`
+	ElementFormDefault   string              `xml:"elementFormDefault,attr,omitempty"`
+	AttributeFormDefault string              `xml:"attributeFormDefault,attr,omitempty"`
+	BlockDefault         string              `xml:"blockDefault,attr,omitempty"` // The block of elements and complex types that don't have their own
+	FinalDefault         string              `xml:"finalDefault,attr,omitempty"` // The final of elements and types that don't have their own
+	Attrs                []SchemaAttr        `xml:",any,attr"`                   // Namespace declarations and anything else on the schema
+	Annotations          []*Annotation       `xml:"annotation,omitempty"`
+	DefaultOpenContent   *DefaultOpenContent `xml:"defaultOpenContent,omitempty"` // XSD 1.1
+	Imports              []*Import           `xml:"import,omitempty"`
+	Includes             []*Include          `xml:"include,omitempty"`
+	Redefines            []*Redefine         `xml:"redefine,omitempty"`
+	Overrides            []*Override         `xml:"override,omitempty"`
+	SimpleTypes          []*SimpleType       `xml:"simpleType,omitempty"`
+	ComplexTypes         []*ComplexType      `xml:"complexType,omitempty"`
+	Elements             []*Element          `xml:"element,omitempty"`
+	Attributes           []*Attribute        `xml:"attribute,omitempty"`
+	Groups               []*Group            `xml:"group,omitempty"`
+	AttributeGroups      []*AttributeGroup   `xml:"attributeGroup,omitempty"`
+	Location             string              `xml:"-"` // Where the schema was loaded from, only set by LoadXSD
+	Schemas              []*XSD              `xml:"-"` // The schema documents combined into this one by LoadXSD, in load order
+	chameleon            bool                // Included into the target namespace of another schema
+	symbols              *symbolTable        // Global components by QName, see Resolve
 }
 
 type Import struct {
@@ -29,30 +48,153 @@ type Import struct {
 	SchemaLocation string `xml:"schemaLocation,attr"`
 }
 
+type Include struct {
+	SchemaLocation string `xml:"schemaLocation,attr"`
+}
+
+// Redefine includes a schema and replaces some of its types
+type Redefine struct {
+	SchemaLocation  string            `xml:"schemaLocation,attr"`
+	SimpleTypes     []*SimpleType     `xml:"simpleType,omitempty"`
+	ComplexTypes    []*ComplexType    `xml:"complexType,omitempty"`
+	Groups          []*Group          `xml:"group,omitempty"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup,omitempty"`
+}
+
+// Override includes a schema and replaces some of its types and elements (XSD 1.1)
+type Override struct {
+	SchemaLocation  string            `xml:"schemaLocation,attr"`
+	SimpleTypes     []*SimpleType     `xml:"simpleType,omitempty"`
+	ComplexTypes    []*ComplexType    `xml:"complexType,omitempty"`
+	Elements        []*Element        `xml:"element,omitempty"`
+	Attributes      []*Attribute      `xml:"attribute,omitempty"`
+	Groups          []*Group          `xml:"group,omitempty"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup,omitempty"`
+}
+
 type ComplexType struct {
-	Name           string          `xml:"name,attr"`
-	Sequence       *Sequence       `xml:"sequence,omitempty"`
-	ComplexContent *ComplexContent `xml:"complexContent,omitempty"`
-	SimpleContent  *SimpleContent  `xml:"simpleContent,omitempty"`
-	Attributes     []*Attribute    `xml:"attribute,omitempty"`
-	Choice         *Choice         `xml:"choice,omitempty"`
+	Name            string            `xml:"name,attr"`
+	Namespace       string            `xml:"-"`                       // Target namespace of the schema
+	Abstract        bool              `xml:"abstract,attr,omitempty"` // An element can't have this type, only a type derived from it
+	Block           string            `xml:"block,attr,omitempty"`    // #all or a list of extension and restriction, derivations that can't be used in place of this type
+	Final           string            `xml:"final,attr,omitempty"`    // #all or a list of extension and restriction, derivations that aren't allowed
+	Mixed           bool              `xml:"mixed,attr,omitempty"`    // Text can appear between the child elements
+	Annotation      *Annotation       `xml:"annotation,omitempty"`
+	OpenContent     *OpenContent      `xml:"openContent,omitempty"` // XSD 1.1
+	Sequence        *Sequence         `xml:"sequence,omitempty"`
+	ComplexContent  *ComplexContent   `xml:"complexContent,omitempty"`
+	SimpleContent   *SimpleContent    `xml:"simpleContent,omitempty"`
+	Attributes      []*Attribute      `xml:"attribute,omitempty"`
+	Choice          *Choice           `xml:"choice,omitempty"`
+	All             *All              `xml:"all,omitempty"`
+	Group           *Group            `xml:"group,omitempty"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup,omitempty"`
+	AnyAttribute    *AnyAttribute     `xml:"anyAttribute,omitempty"`
+	Asserts         []*Assert         `xml:"assert,omitempty"` // XSD 1.1
+}
+
+// Assert is an XSD 1.1 XPath test that an element of a complex type has to pass
+type Assert struct {
+	Test                  string      `xml:"test,attr"`
+	XPathDefaultNamespace string      `xml:"xpathDefaultNamespace,attr,omitempty"`
+	Annotation            *Annotation `xml:"annotation,omitempty"`
+}
+
+// Assertion is an XSD 1.1 facet, an XPath test that a value has to pass, the value is $value in the test
+type Assertion struct {
+	Test                  string      `xml:"test,attr"`
+	XPathDefaultNamespace string      `xml:"xpathDefaultNamespace,attr,omitempty"`
+	Annotation            *Annotation `xml:"annotation,omitempty"`
+}
+
+// Alternative is an XSD 1.1 conditional type, the element has the type of the first alternative whose test passes.
+// An alternative without a test is the default
+type Alternative struct {
+	Test                  string       `xml:"test,attr,omitempty"`
+	Type                  string       `xml:"type,attr,omitempty"`
+	XPathDefaultNamespace string       `xml:"xpathDefaultNamespace,attr,omitempty"`
+	TypeQName             QName        `xml:"-"`
+	Annotation            *Annotation  `xml:"annotation,omitempty"`
+	ComplexType           *ComplexType `xml:"complexType,omitempty"`
+	SimpleType            *SimpleType  `xml:"simpleType,omitempty"`
+}
+
+// OpenContent is XSD 1.1 content that allows elements matching the wildcard
+// between (interleave) or after (suffix) the elements of the content model, or not at all (none)
+type OpenContent struct {
+	Mode       string      `xml:"mode,attr,omitempty"` // interleave (default), suffix or none
+	Annotation *Annotation `xml:"annotation,omitempty"`
+	Any        *Any        `xml:"any,omitempty"`
+}
+
+// DefaultOpenContent is the open content of every complex type in the schema without its own
+type DefaultOpenContent struct {
+	AppliesToEmpty bool        `xml:"appliesToEmpty,attr,omitempty"`
+	Mode           string      `xml:"mode,attr,omitempty"` // interleave (default) or suffix
+	Annotation     *Annotation `xml:"annotation,omitempty"`
+	Any            *Any        `xml:"any,omitempty"`
 }
 
 type SimpleType struct {
 	Name        string       `xml:"name,attr"`
+	Namespace   string       `xml:"-"`                    // Target namespace of the schema
+	Final       string       `xml:"final,attr,omitempty"` // #all or a list of restriction, list and union, derivations that aren't allowed
+	Annotation  *Annotation  `xml:"annotation,omitempty"`
 	Restriction *Restriction `xml:"restriction,omitempty"`
+	List        *List        `xml:"list,omitempty"`
+	Union       *Union       `xml:"union,omitempty"`
+}
+
+// List is a simple type whose values are white space separated lists of ItemType or the inline SimpleType
+type List struct {
+	ItemType      string      `xml:"itemType,attr,omitempty"`
+	ItemTypeQName QName       `xml:"-"`
+	Annotation    *Annotation `xml:"annotation,omitempty"`
+	SimpleType    *SimpleType `xml:"simpleType,omitempty"`
+}
+
+// Union is a simple type whose values are a value of one of the member types or inline simple types
+type Union struct {
+	MemberTypes      string        `xml:"memberTypes,attr,omitempty"` // White space separated
+	MemberTypeQNames []QName       `xml:"-"`
+	Annotation       *Annotation   `xml:"annotation,omitempty"`
+	SimpleTypes      []*SimpleType `xml:"simpleType,omitempty"`
 }
 
 type Enumeration struct {
-	Value string `xml:"value,attr"`
+	Value      string      `xml:"value,attr"`
+	Annotation *Annotation `xml:"annotation,omitempty"`
 }
 
 type Restriction struct {
-	Base         string         `xml:"base,attr"`
-	Enumerations []*Enumeration `xml:"enumeration,omitempty"`
-	MinInclusive *MinInclusive  `xml:"minInclusive,omitempty"`
-	MaxInclusive *MaxInclusive  `xml:"maxInclusive,omitempty"`
-	Pattern      *Pattern       `xml:"pattern,omitempty"`
+	Base           string          `xml:"base,attr"`
+	BaseQName      QName           `xml:"-"`
+	Annotation     *Annotation     `xml:"annotation,omitempty"`
+	Enumerations   []*Enumeration  `xml:"enumeration,omitempty"`
+	MinInclusive   *MinInclusive   `xml:"minInclusive,omitempty"`
+	MaxInclusive   *MaxInclusive   `xml:"maxInclusive,omitempty"`
+	MinExclusive   *MinExclusive   `xml:"minExclusive,omitempty"`
+	MaxExclusive   *MaxExclusive   `xml:"maxExclusive,omitempty"`
+	Patterns       []*Pattern      `xml:"pattern,omitempty"` // A value has to match one of the patterns
+	Length         *Length         `xml:"length,omitempty"`
+	MinLength      *MinLength      `xml:"minLength,omitempty"`
+	MaxLength      *MaxLength      `xml:"maxLength,omitempty"`
+	TotalDigits    *TotalDigits    `xml:"totalDigits,omitempty"`
+	FractionDigits *FractionDigits `xml:"fractionDigits,omitempty"`
+	WhiteSpace     *WhiteSpace     `xml:"whiteSpace,omitempty"`
+	Assertions     []*Assertion    `xml:"assertion,omitempty"` // XSD 1.1
+	// Restricting a simpleContent type can have a simple type, restricting either content type can have attributes
+	SimpleType      *SimpleType       `xml:"simpleType,omitempty"`
+	Attributes      []*Attribute      `xml:"attribute,omitempty"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup,omitempty"`
+	AnyAttribute    *AnyAttribute     `xml:"anyAttribute,omitempty"`
+	// Restricting a complexContent type gives the complete restricted content
+	OpenContent *OpenContent `xml:"openContent,omitempty"`
+	Sequence    *Sequence    `xml:"sequence,omitempty"`
+	Choice      *Choice      `xml:"choice,omitempty"`
+	All         *All         `xml:"all,omitempty"`
+	Group       *Group       `xml:"group,omitempty"`
+	Asserts     []*Assert    `xml:"assert,omitempty"`
 }
 
 type MinInclusive struct {
@@ -63,65 +205,253 @@ type MaxInclusive struct {
 	Value string `xml:"value,attr"`
 }
 
+type MinExclusive struct {
+	Value string `xml:"value,attr"`
+}
+
+type MaxExclusive struct {
+	Value string `xml:"value,attr"`
+}
+
 type Pattern struct {
 	Value string `xml:"value,attr"`
 }
 
+type Length struct {
+	Value string `xml:"value,attr"`
+}
+
+type MinLength struct {
+	Value string `xml:"value,attr"`
+}
+
+type MaxLength struct {
+	Value string `xml:"value,attr"`
+}
+
+type TotalDigits struct {
+	Value string `xml:"value,attr"`
+}
+
+type FractionDigits struct {
+	Value string `xml:"value,attr"`
+}
+
+// WhiteSpace is preserve, replace or collapse
+type WhiteSpace struct {
+	Value string `xml:"value,attr"`
+}
+
 type Sequence struct {
-	Name      string     `xml:"name,attr"`
-	MinOccurs string     `xml:"minOccurs,attr"`
-	MaxOccurs string     `xml:"maxOccurs,attr"`
-	Elements  []*Element `xml:"element,omitempty"`
-	Choice    *Choice    `xml:"choice,omitempty"`
+	Name       string      `xml:"name,attr"`
+	MinOccurs  string      `xml:"minOccurs,attr"`
+	MaxOccurs  string      `xml:"maxOccurs,attr"`
+	Annotation *Annotation `xml:"annotation,omitempty"`
+	Elements   []*Element  `xml:"element,omitempty"`
+	Sequences  []*Sequence `xml:"sequence,omitempty"`
+	Choices    []*Choice   `xml:"choice,omitempty"`
+	Groups     []*Group    `xml:"group,omitempty"`
+	Anys       []*Any      `xml:"any,omitempty"`
+	position   int         // See Element.position
+}
+
+// All is the xs:all compositor, the elements can appear in any order
+type All struct {
+	MinOccurs  string      `xml:"minOccurs,attr"`
+	MaxOccurs  string      `xml:"maxOccurs,attr"`
+	Annotation *Annotation `xml:"annotation,omitempty"`
+	Elements   []*Element  `xml:"element,omitempty"`
 }
 
 type Element struct {
-	Name        string       `xml:"name,attr"`
-	Type        string       `xml:"type,attr"`
-	Ref         string       `xml:"ref,attr"`
-	MinOccurs   string       `xml:"minOccurs,attr"`
-	MaxOccurs   string       `xml:"maxOccurs,attr"`
-	ComplexType *ComplexType `xml:"complexType,omitempty"`
-	SimpleType  *SimpleType  `xml:"simpleType,omitempty"`
-	Annotation  *Annotation  `xml:"annotation,omitempty"`
+	Name                    string         `xml:"name,attr"`
+	Type                    string         `xml:"type,attr"`
+	Ref                     string         `xml:"ref,attr"`
+	Form                    string         `xml:"form,attr,omitempty"`
+	Namespace               string         `xml:"-"` // Namespace of the element name, blank if unqualified
+	TypeQName               QName          `xml:"-"`
+	RefQName                QName          `xml:"-"`
+	RefElement              *Element       `xml:"-"` // The global element for Ref, see Resolve
+	MinOccurs               string         `xml:"minOccurs,attr"`
+	MaxOccurs               string         `xml:"maxOccurs,attr"`
+	Nillable                bool           `xml:"nillable,attr,omitempty"`
+	Default                 string         `xml:"default,attr,omitempty"`
+	Fixed                   string         `xml:"fixed,attr,omitempty"`
+	Abstract                bool           `xml:"abstract,attr,omitempty"`          // The element can't appear in a document, only members of its substitution group
+	SubstitutionGroup       string         `xml:"substitutionGroup,attr,omitempty"` // The global elements this element can be substituted for, a list in XSD 1.1
+	SubstitutionGroupQNames []QName        `xml:"-"`
+	SubstitutionGroupHeads  []*Element     `xml:"-"`                    // The global elements for SubstitutionGroup, see Resolve
+	Block                   string         `xml:"block,attr,omitempty"` // #all or a list of extension, restriction and substitution
+	Final                   string         `xml:"final,attr,omitempty"` // #all or a list of extension and restriction
+	Annotation              *Annotation    `xml:"annotation,omitempty"`
+	ComplexType             *ComplexType   `xml:"complexType,omitempty"`
+	SimpleType              *SimpleType    `xml:"simpleType,omitempty"`
+	Alternatives            []*Alternative `xml:"alternative,omitempty"` // XSD 1.1
+	Uniques                 []*Unique      `xml:"unique,omitempty"`
+	Keys                    []*Key         `xml:"key,omitempty"`
+	KeyRefs                 []*KeyRef      `xml:"keyref,omitempty"`
+	position                int            // Where the particle is in its sequence or choice, see particles.go
+}
+
+// Unique says the fields of the selected elements are unique within the element, if they are present
+type Unique struct {
+	Name      string    `xml:"name,attr"`
+	Namespace string    `xml:"-"` // Target namespace of the schema
+	Selector  *Selector `xml:"selector"`
+	Fields    []*Field  `xml:"field"`
+}
+
+// Key says the fields of the selected elements are present and unique within the element
+type Key struct {
+	Name      string    `xml:"name,attr"`
+	Namespace string    `xml:"-"` // Target namespace of the schema
+	Selector  *Selector `xml:"selector"`
+	Fields    []*Field  `xml:"field"`
 }
 
+// KeyRef says the fields of the selected elements match the fields of a key or unique constraint
+type KeyRef struct {
+	Name       string     `xml:"name,attr"`
+	Refer      string     `xml:"refer,attr"`
+	Namespace  string     `xml:"-"` // Target namespace of the schema
+	ReferQName QName      `xml:"-"`
+	ReferTo    XsdElement `xml:"-"` // The *Key or *Unique for Refer, see Resolve
+	Selector   *Selector  `xml:"selector"`
+	Fields     []*Field   `xml:"field"`
+}
+
+// Selector is the restricted XPath finding the elements an identity constraint applies to
+type Selector struct {
+	XPath string `xml:"xpath,attr"`
+}
+
+// Field is the restricted XPath finding an element or attribute value of a selected element
+type Field struct {
+	XPath string `xml:"xpath,attr"`
+}
+
+// Annotation is the documentation for people and the appinfo for applications of a schema component
 type Annotation struct {
-	Documentation string `xml:"documentation"`
+	Documentation []*Documentation `xml:"documentation,omitempty"`
+	AppInfos      []*AppInfo       `xml:"appinfo,omitempty"`
+}
+
+type Documentation struct {
+	Lang   string `xml:"http://www.w3.org/XML/1998/namespace lang,attr,omitempty"` // xml:lang
+	Source string `xml:"source,attr,omitempty"`
+	Value  string `xml:",chardata"`
+}
+
+// AppInfo holds the content for applications as it is written in the schema
+type AppInfo struct {
+	Source  string `xml:"source,attr,omitempty"`
+	Content string `xml:",innerxml"`
 }
 
 type Choice struct {
-	Name      string     `xml:"name,attr"`
-	MinOccurs string     `xml:"minOccurs,attr"`
-	MaxOccurs string     `xml:"maxOccurs,attr"`
-	Elements  []*Element `xml:"element,omitempty"`
-	Sequence  *Sequence  `xml:"sequence,omitempty"`
+	Name       string      `xml:"name,attr"`
+	MinOccurs  string      `xml:"minOccurs,attr"`
+	MaxOccurs  string      `xml:"maxOccurs,attr"`
+	Annotation *Annotation `xml:"annotation,omitempty"`
+	Elements   []*Element  `xml:"element,omitempty"`
+	Sequences  []*Sequence `xml:"sequence,omitempty"`
+	Choices    []*Choice   `xml:"choice,omitempty"`
+	Groups     []*Group    `xml:"group,omitempty"`
+	Anys       []*Any      `xml:"any,omitempty"`
+	position   int         // See Element.position
+}
+
+// Any is a wildcard allowing elements from other namespaces
+type Any struct {
+	Namespace       string      `xml:"namespace,attr,omitempty"`       // ##any (default), ##other, or a list of namespaces, ##targetNamespace and ##local
+	ProcessContents string      `xml:"processContents,attr,omitempty"` // strict (default), lax or skip
+	MinOccurs       string      `xml:"minOccurs,attr,omitempty"`
+	MaxOccurs       string      `xml:"maxOccurs,attr,omitempty"`
+	Annotation      *Annotation `xml:"annotation,omitempty"`
+	position        int         // See Element.position
+}
+
+// AnyAttribute is a wildcard allowing attributes from other namespaces
+type AnyAttribute struct {
+	Namespace       string      `xml:"namespace,attr,omitempty"`
+	ProcessContents string      `xml:"processContents,attr,omitempty"`
+	Annotation      *Annotation `xml:"annotation,omitempty"`
+}
+
+// Group is a named model group definition or, when Ref is set, a reference to one
+type Group struct {
+	Name       string      `xml:"name,attr,omitempty"`
+	Ref        string      `xml:"ref,attr,omitempty"`
+	Namespace  string      `xml:"-"` // Target namespace of the schema
+	RefQName   QName       `xml:"-"`
+	RefGroup   *Group      `xml:"-"` // The group definition for Ref, see Resolve
+	MinOccurs  string      `xml:"minOccurs,attr,omitempty"`
+	MaxOccurs  string      `xml:"maxOccurs,attr,omitempty"`
+	Annotation *Annotation `xml:"annotation,omitempty"`
+	Sequence   *Sequence   `xml:"sequence,omitempty"`
+	Choice     *Choice     `xml:"choice,omitempty"`
+	All        *All        `xml:"all,omitempty"`
+	position   int         // See Element.position
+}
+
+// AttributeGroup is a named attribute group definition or, when Ref is set, a reference to one
+type AttributeGroup struct {
+	Name              string            `xml:"name,attr,omitempty"`
+	Ref               string            `xml:"ref,attr,omitempty"`
+	Namespace         string            `xml:"-"` // Target namespace of the schema
+	RefQName          QName             `xml:"-"`
+	RefAttributeGroup *AttributeGroup   `xml:"-"` // The attribute group definition for Ref, see Resolve
+	Annotation        *Annotation       `xml:"annotation,omitempty"`
+	Attributes        []*Attribute      `xml:"attribute,omitempty"`
+	AttributeGroups   []*AttributeGroup `xml:"attributeGroup,omitempty"`
+	AnyAttribute      *AnyAttribute     `xml:"anyAttribute,omitempty"`
 }
 
 type SimpleContent struct {
-	Extension *Extension `xml:"extension,omitempty"`
+	Annotation  *Annotation  `xml:"annotation,omitempty"`
+	Extension   *Extension   `xml:"extension,omitempty"`
+	Restriction *Restriction `xml:"restriction,omitempty"`
 }
 
 type ComplexContent struct {
-	Extension *Extension `xml:"extension,omitempty"`
+	Mixed       *bool        `xml:"mixed,attr,omitempty"` // Overrides mixed on the complex type when it's there
+	Annotation  *Annotation  `xml:"annotation,omitempty"`
+	Extension   *Extension   `xml:"extension,omitempty"`
+	Restriction *Restriction `xml:"restriction,omitempty"`
 }
 
 // This
 type Extension struct {
-	ID         string       `xml:"id,attr"`
-	Base       string       `xml:"base,attr"`
-	Sequence   *Sequence    `xml:"sequence,omitempty"`
-	Attributes []*Attribute `xml:"attribute,omitempty"`
+	ID              string            `xml:"id,attr"`
+	Base            string            `xml:"base,attr"`
+	BaseQName       QName             `xml:"-"`
+	Annotation      *Annotation       `xml:"annotation,omitempty"`
+	OpenContent     *OpenContent      `xml:"openContent,omitempty"` // XSD 1.1
+	Sequence        *Sequence         `xml:"sequence,omitempty"`
+	Choice          *Choice           `xml:"choice,omitempty"`
+	All             *All              `xml:"all,omitempty"`
+	Group           *Group            `xml:"group,omitempty"`
+	Attributes      []*Attribute      `xml:"attribute,omitempty"`
+	AttributeGroups []*AttributeGroup `xml:"attributeGroup,omitempty"`
+	AnyAttribute    *AnyAttribute     `xml:"anyAttribute,omitempty"`
+	Asserts         []*Assert         `xml:"assert,omitempty"` // XSD 1.1
 }
 
 type Attribute struct {
-	Name        string       `xml:"name,attr"`
-	Type        string       `xml:"type,attr"`
-	Use         string       `xml:"use,attr,omitempty"`
-	Ref         string       `xml:"ref,attr"`
-	ComplexType *ComplexType `xml:"complexType,omitempty"`
-	SimpleType  *SimpleType  `xml:"simpleType,omitempty"`
-	Annotation  *Annotation  `xml:"annotation,omitempty"`
+	Name         string       `xml:"name,attr"`
+	Type         string       `xml:"type,attr"`
+	Use          string       `xml:"use,attr,omitempty"`
+	Ref          string       `xml:"ref,attr"`
+	Default      string       `xml:"default,attr,omitempty"`
+	Fixed        string       `xml:"fixed,attr,omitempty"`
+	Form         string       `xml:"form,attr,omitempty"`
+	Namespace    string       `xml:"-"` // Namespace of the attribute name, blank if unqualified
+	TypeQName    QName        `xml:"-"`
+	RefQName     QName        `xml:"-"`
+	RefAttribute *Attribute   `xml:"-"` // The global attribute for Ref, see Resolve
+	Annotation   *Annotation  `xml:"annotation,omitempty"`
+	ComplexType  *ComplexType `xml:"complexType,omitempty"`
+	SimpleType   *SimpleType  `xml:"simpleType,omitempty"`
 }
 
 func (xsd *XSD) ToString() string {
@@ -132,6 +462,18 @@ func (i *Import) ToString() string {
 	return fmt.Sprintf("Import: Namespace:%s, Location:%s", i.Namespace, i.SchemaLocation)
 }
 
+func (i *Include) ToString() string {
+	return fmt.Sprintf("Include: Location:%s", i.SchemaLocation)
+}
+
+func (r *Redefine) ToString() string {
+	return fmt.Sprintf("Redefine: Location:%s", r.SchemaLocation)
+}
+
+func (o *Override) ToString() string {
+	return fmt.Sprintf("Override: Location:%s", o.SchemaLocation)
+}
+
 func (ct *ComplexType) ToString() string {
 	return fmt.Sprintf("ComplexType: %s", ct.Name)
 }
@@ -141,6 +483,12 @@ func (st *SimpleType) ToString() string {
 func (r *Restriction) ToString() string {
 	return fmt.Sprintf("Restriction: %s", r.Base)
 }
+func (l *List) ToString() string {
+	return fmt.Sprintf("List: %s", l.ItemType)
+}
+func (u *Union) ToString() string {
+	return fmt.Sprintf("Union: %s", u.MemberTypes)
+}
 func (e *Enumeration) ToString() string {
 	return fmt.Sprintf("Enumeration: %s", e.Value)
 }
@@ -150,11 +498,47 @@ func (s *Sequence) ToString() string {
 func (e *Element) ToString() string {
 	return fmt.Sprintf("Element: %s (%s) %s", e.Name, e.Type, occurs(e.MinOccurs, e.MaxOccurs))
 }
+func (u *Unique) ToString() string {
+	return fmt.Sprintf("Unique: %s", u.Name)
+}
+func (k *Key) ToString() string {
+	return fmt.Sprintf("Key: %s", k.Name)
+}
+func (kr *KeyRef) ToString() string {
+	return fmt.Sprintf("KeyRef: %s refer %s", kr.Name, kr.Refer)
+}
+func (s *Selector) ToString() string {
+	return fmt.Sprintf("Selector: %s", s.XPath)
+}
+func (fd *Field) ToString() string {
+	return fmt.Sprintf("Field: %s", fd.XPath)
+}
 func (ch *Choice) ToString() string {
 	return fmt.Sprintf("Choice: %s %s", ch.Name, occurs(ch.MinOccurs, ch.MaxOccurs))
 }
+func (g *Group) ToString() string {
+	if g.Ref > "" {
+		return fmt.Sprintf("Group: ref %s %s", g.Ref, occurs(g.MinOccurs, g.MaxOccurs))
+	}
+	return fmt.Sprintf("Group: %s", g.Name)
+}
+func (ag *AttributeGroup) ToString() string {
+	if ag.Ref > "" {
+		return fmt.Sprintf("AttributeGroup: ref %s", ag.Ref)
+	}
+	return fmt.Sprintf("AttributeGroup: %s", ag.Name)
+}
+func (a *Any) ToString() string {
+	return fmt.Sprintf("Any: %s %s %s", a.Namespace, a.ProcessContents, occurs(a.MinOccurs, a.MaxOccurs))
+}
+func (aa *AnyAttribute) ToString() string {
+	return fmt.Sprintf("AnyAttribute: %s %s", aa.Namespace, aa.ProcessContents)
+}
+func (al *All) ToString() string {
+	return fmt.Sprintf("All: %s", occurs(al.MinOccurs, al.MaxOccurs))
+}
 func (a *Annotation) ToString() string {
-	return fmt.Sprintf("Annotation: %s", a.Documentation)
+	return fmt.Sprintf("Annotation: %s", a.Description(""))
 }
 func (cc *ComplexContent) ToString() string {
 	return "ComplexContent"
@@ -168,9 +552,72 @@ func (ex *Extension) ToString() string {
 func (a *Attribute) ToString() string {
 	return fmt.Sprintf("Attribute: %s", a.Name)
 }
-func (p *Pattern) ToString() string       { return fmt.Sprintf("Pattern: %s", p.Value) }
-func (mi *MinInclusive) ToString() string { return fmt.Sprintf("MinInclusive: %s", mi.Value) }
-func (mi *MaxInclusive) ToString() string { return fmt.Sprintf("MaxInclusive: %s", mi.Value) }
+func (p *Pattern) ToString() string         { return fmt.Sprintf("Pattern: %s", p.Value) }
+func (mi *MinInclusive) ToString() string   { return fmt.Sprintf("MinInclusive: %s", mi.Value) }
+func (mi *MaxInclusive) ToString() string   { return fmt.Sprintf("MaxInclusive: %s", mi.Value) }
+func (me *MinExclusive) ToString() string   { return fmt.Sprintf("MinExclusive: %s", me.Value) }
+func (me *MaxExclusive) ToString() string   { return fmt.Sprintf("MaxExclusive: %s", me.Value) }
+func (l *Length) ToString() string          { return fmt.Sprintf("Length: %s", l.Value) }
+func (ml *MinLength) ToString() string      { return fmt.Sprintf("MinLength: %s", ml.Value) }
+func (ml *MaxLength) ToString() string      { return fmt.Sprintf("MaxLength: %s", ml.Value) }
+func (td *TotalDigits) ToString() string    { return fmt.Sprintf("TotalDigits: %s", td.Value) }
+func (fd *FractionDigits) ToString() string { return fmt.Sprintf("FractionDigits: %s", fd.Value) }
+func (ws *WhiteSpace) ToString() string     { return fmt.Sprintf("WhiteSpace: %s", ws.Value) }
+func (as *Assertion) ToString() string      { return fmt.Sprintf("Assertion: %s", as.Test) }
+
+func (as *Assert) ToString() string {
+	return fmt.Sprintf("Assert: %s", as.Test)
+}
+func (alt *Alternative) ToString() string {
+	return fmt.Sprintf("Alternative: %s (%s)", alt.Test, alt.Type)
+}
+func (oc *OpenContent) ToString() string {
+	return fmt.Sprintf("OpenContent: %s", oc.Mode)
+}
+func (doc *DefaultOpenContent) ToString() string {
+	return fmt.Sprintf("DefaultOpenContent: %s", doc.Mode)
+}
+
+// facets returns the facets of the restriction other than enumerations
+func (r *Restriction) facets() (facets []XsdElement) {
+	for _, p := range r.Patterns {
+		facets = append(facets, p)
+	}
+	if r.MinInclusive != nil {
+		facets = append(facets, r.MinInclusive)
+	}
+	if r.MaxInclusive != nil {
+		facets = append(facets, r.MaxInclusive)
+	}
+	if r.MinExclusive != nil {
+		facets = append(facets, r.MinExclusive)
+	}
+	if r.MaxExclusive != nil {
+		facets = append(facets, r.MaxExclusive)
+	}
+	if r.Length != nil {
+		facets = append(facets, r.Length)
+	}
+	if r.MinLength != nil {
+		facets = append(facets, r.MinLength)
+	}
+	if r.MaxLength != nil {
+		facets = append(facets, r.MaxLength)
+	}
+	if r.TotalDigits != nil {
+		facets = append(facets, r.TotalDigits)
+	}
+	if r.FractionDigits != nil {
+		facets = append(facets, r.FractionDigits)
+	}
+	if r.WhiteSpace != nil {
+		facets = append(facets, r.WhiteSpace)
+	}
+	for _, as := range r.Assertions {
+		facets = append(facets, as)
+	}
+	return
+}
 
 func occurs(minOccurs, maxOccurs string) string {
 	if minOccurs == "" && maxOccurs == "" {
@@ -191,6 +638,22 @@ func occurs(minOccurs, maxOccurs string) string {
 	return fmt.Sprintf("%s:%s", minOccurs, maxOccurs)
 }
 
+// IsMixed is true if text can appear between the child elements, mixed on the complex content overrides mixed on the complex type
+func (ct *ComplexType) IsMixed() bool {
+	if ct.ComplexContent != nil && ct.ComplexContent.Mixed != nil {
+		return *ct.ComplexContent.Mixed
+	}
+	return ct.Mixed
+}
+
+// QName is the name of the attribute, the referenced attribute's name for a reference
+func (a *Attribute) QName() QName {
+	if a.Ref > "" {
+		return a.RefQName
+	}
+	return QName{Namespace: a.Namespace, Local: a.Name}
+}
+
 // xsdItem methods
 func (a *Attribute) IsMandatoryOptional() string {
 	if a.Use == "required" {
@@ -205,24 +668,80 @@ func (a *Attribute) IsRepeated() bool {
 
 func (e *Element) IsMandatoryOptional() string {
 	//if e.Use == "required" {return "M"}
-	if e.MinOccurs == "0" {
+	return mandatoryOptional(e.MinOccurs)
+}
+
+func (e *Element) IsRepeated() bool {
+	return repeated(e.MaxOccurs)
+}
+
+func (a *Any) IsMandatoryOptional() string {
+	return mandatoryOptional(a.MinOccurs)
+}
+
+func (a *Any) IsRepeated() bool {
+	return repeated(a.MaxOccurs)
+}
+
+func mandatoryOptional(minOccurs string) string {
+	if minOccurs == "0" {
 		return "O"
 	}
-	if e.MinOccurs > "" {
+	if minOccurs > "" {
 		return "M"
 	}
 	return ""
 }
 
-func (e *Element) IsRepeated() bool {
-	if e.MaxOccurs == "" {
+func repeated(maxOccurs string) bool {
+	if maxOccurs == "" {
 		return false
 	}
-	if e.MaxOccurs == "unbounded" {
+	if maxOccurs == "unbounded" {
 		return true
 	}
-	if i, err := strconv.Atoi(e.MaxOccurs); err == nil && i > 1 {
+	if i, err := strconv.Atoi(maxOccurs); err == nil && i > 1 {
 		return true
 	}
 	return false
 }
+
+// Description is the documentation in the language, or with no language, joined by new lines.
+// If there isn't any then it's the first documentation whatever the language
+func (a *Annotation) Description(lang string) string {
+	if a == nil {
+		return ""
+	}
+	var sSlice []string
+	for _, d := range a.Documentation {
+		if d.Lang == lang {
+			sSlice = append(sSlice, strings.TrimSpace(d.Value))
+		}
+	}
+	if len(sSlice) == 0 && len(a.Documentation) > 0 {
+		return strings.TrimSpace(a.Documentation[0].Value)
+	}
+	return strings.Join(sSlice, "\n")
+}
+
+// Descriptions is the documentation by xml:lang, nil unless at least one documentation has a language
+func (a *Annotation) Descriptions() (descriptions map[string]string) {
+	if a == nil {
+		return nil
+	}
+	for _, d := range a.Documentation {
+		if d.Lang > "" {
+			descriptions = make(map[string]string)
+			break
+		}
+	}
+	if descriptions == nil {
+		return
+	}
+	for _, d := range a.Documentation {
+		if _, inMap := descriptions[d.Lang]; !inMap {
+			descriptions[d.Lang] = a.Description(d.Lang)
+		}
+	}
+	return
+}
@@ -9,6 +9,8 @@ func NewXSD(xsdXML []byte) (xsd *XSD, err error) {
 	if err = xml.Unmarshal(xsdXML, &xsd); err != nil {
 		return nil, fmt.Errorf("could not unmarshal XML into XSD, got %v", err)
 	}
+	xsd.resolveQNames()
+	_ = xsd.Resolve() // References to imported schemas can't be resolved yet
 	return
 }
 
@@ -20,11 +22,36 @@ func (xsd *XSD) ApplyFunction(f func(XsdElement) error) (err error) {
 	if err = f(xsd); err != nil {
 		return
 	}
+	for _, a := range xsd.Annotations {
+		if err = a.applyFunction(f); err != nil {
+			return
+		}
+	}
+	if err = xsd.DefaultOpenContent.applyFunction(f); err != nil {
+		return
+	}
 	//if err = f(xsd.XMLName); err != nil {
 	//	return
 	//}
-	if err = xsd.Import.applyFunction(f); err != nil {
-		return
+	for _, i := range xsd.Imports {
+		if err = i.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, i := range xsd.Includes {
+		if err = i.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, r := range xsd.Redefines {
+		if err = r.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, o := range xsd.Overrides {
+		if err = o.applyFunction(f); err != nil {
+			return
+		}
 	}
 	for _, ct := range xsd.ComplexTypes {
 		if err = ct.applyFunction(f); err != nil {
@@ -41,6 +68,21 @@ func (xsd *XSD) ApplyFunction(f func(XsdElement) error) (err error) {
 			return
 		}
 	}
+	for _, a := range xsd.Attributes {
+		if err = a.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, g := range xsd.Groups {
+		if err = g.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, ag := range xsd.AttributeGroups {
+		if err = ag.applyFunction(f); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -51,6 +93,29 @@ func (i *Import) applyFunction(f func(XsdElement) error) (err error) {
 	return f(i)
 }
 
+func (i *Include) applyFunction(f func(XsdElement) error) (err error) {
+	if i == nil {
+		return nil
+	}
+	return f(i)
+}
+
+// applyFunction applies a function to Redefine, the redefined types are walked as part of the combined schema
+func (r *Redefine) applyFunction(f func(XsdElement) error) (err error) {
+	if r == nil {
+		return nil
+	}
+	return f(r)
+}
+
+// applyFunction applies a function to Override, the overriding components are walked as part of the combined schema
+func (o *Override) applyFunction(f func(XsdElement) error) (err error) {
+	if o == nil {
+		return nil
+	}
+	return f(o)
+}
+
 // applyFunction applies a function ComplexType and children as long as function returns true
 func (ct *ComplexType) applyFunction(f func(XsdElement) error) (err error) {
 	if ct == nil {
@@ -59,6 +124,12 @@ func (ct *ComplexType) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(ct); err != nil {
 		return
 	}
+	if err = ct.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	if err = ct.OpenContent.applyFunction(f); err != nil {
+		return
+	}
 	if err = ct.Sequence.applyFunction(f); err != nil {
 		return
 	}
@@ -68,6 +139,9 @@ func (ct *ComplexType) applyFunction(f func(XsdElement) error) (err error) {
 	if err = ct.SimpleContent.applyFunction(f); err != nil {
 		return
 	}
+	if err = ct.Group.applyFunction(f); err != nil {
+		return
+	}
 	for _, a := range ct.Attributes {
 		if err = a.applyFunction(f); err != nil {
 			return
@@ -76,6 +150,22 @@ func (ct *ComplexType) applyFunction(f func(XsdElement) error) (err error) {
 	if err = ct.Choice.applyFunction(f); err != nil {
 		return
 	}
+	if err = ct.All.applyFunction(f); err != nil {
+		return
+	}
+	for _, ag := range ct.AttributeGroups {
+		if err = ag.applyFunction(f); err != nil {
+			return
+		}
+	}
+	if err = ct.AnyAttribute.applyFunction(f); err != nil {
+		return
+	}
+	for _, as := range ct.Asserts {
+		if err = as.applyFunction(f); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -87,9 +177,49 @@ func (st *SimpleType) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(st); err != nil {
 		return
 	}
+	if err = st.Annotation.applyFunction(f); err != nil {
+		return
+	}
 	if err = st.Restriction.applyFunction(f); err != nil {
 		return
 	}
+	if err = st.List.applyFunction(f); err != nil {
+		return
+	}
+	if err = st.Union.applyFunction(f); err != nil {
+		return
+	}
+	return
+}
+
+func (l *List) applyFunction(f func(XsdElement) error) (err error) {
+	if l == nil {
+		return nil
+	}
+	if err = f(l); err != nil {
+		return
+	}
+	if err = l.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	return l.SimpleType.applyFunction(f)
+}
+
+func (u *Union) applyFunction(f func(XsdElement) error) (err error) {
+	if u == nil {
+		return nil
+	}
+	if err = f(u); err != nil {
+		return
+	}
+	if err = u.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	for _, st := range u.SimpleTypes {
+		if err = st.applyFunction(f); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -101,11 +231,55 @@ func (r *Restriction) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(r); err != nil {
 		return
 	}
+	if err = r.Annotation.applyFunction(f); err != nil {
+		return
+	}
 	for _, e := range r.Enumerations {
 		if err = e.applyFunction(f); err != nil {
 			return
 		}
 	}
+	for _, facet := range r.facets() {
+		if err = f(facet); err != nil {
+			return
+		}
+	}
+	if err = r.SimpleType.applyFunction(f); err != nil {
+		return
+	}
+	if err = r.OpenContent.applyFunction(f); err != nil {
+		return
+	}
+	if err = r.Sequence.applyFunction(f); err != nil {
+		return
+	}
+	if err = r.Choice.applyFunction(f); err != nil {
+		return
+	}
+	if err = r.All.applyFunction(f); err != nil {
+		return
+	}
+	if err = r.Group.applyFunction(f); err != nil {
+		return
+	}
+	for _, a := range r.Attributes {
+		if err = a.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, ag := range r.AttributeGroups {
+		if err = ag.applyFunction(f); err != nil {
+			return
+		}
+	}
+	if err = r.AnyAttribute.applyFunction(f); err != nil {
+		return
+	}
+	for _, as := range r.Asserts {
+		if err = as.applyFunction(f); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -117,6 +291,9 @@ func (e *Enumeration) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(e); err != nil {
 		return
 	}
+	if err = e.Annotation.applyFunction(f); err != nil {
+		return
+	}
 	return
 }
 
@@ -127,14 +304,14 @@ func (s *Sequence) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(s); err != nil {
 		return
 	}
-	for _, e := range s.Elements {
-		if err = e.applyFunction(f); err != nil {
+	if err = s.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	for _, p := range s.Particles() {
+		if err = applyFunctionParticle(f, p); err != nil {
 			return
 		}
 	}
-	if err = s.Choice.applyFunction(f); err != nil {
-		return
-	}
 	return
 }
 
@@ -151,9 +328,29 @@ func (e *Element) applyFunction(f func(XsdElement) error) (err error) {
 	if err = e.SimpleType.applyFunction(f); err != nil {
 		return
 	}
+	for _, alt := range e.Alternatives {
+		if err = alt.applyFunction(f); err != nil {
+			return
+		}
+	}
 	if err = e.Annotation.applyFunction(f); err != nil {
 		return
 	}
+	for _, u := range e.Uniques {
+		if err = u.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, k := range e.Keys {
+		if err = k.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, kr := range e.KeyRefs {
+		if err = kr.applyFunction(f); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -164,7 +361,13 @@ func (cc *ComplexContent) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(cc); err != nil {
 		return
 	}
-	err = cc.Extension.applyFunction(f)
+	if err = cc.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	if err = cc.Extension.applyFunction(f); err != nil {
+		return
+	}
+	err = cc.Restriction.applyFunction(f)
 	return
 }
 
@@ -175,7 +378,13 @@ func (sc *SimpleContent) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(sc); err != nil {
 		return
 	}
-	err = sc.Extension.applyFunction(f)
+	if err = sc.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	if err = sc.Extension.applyFunction(f); err != nil {
+		return
+	}
+	err = sc.Restriction.applyFunction(f)
 	return
 }
 
@@ -186,14 +395,42 @@ func (ex *Extension) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(ex); err != nil {
 		return
 	}
+	if err = ex.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	if err = ex.OpenContent.applyFunction(f); err != nil {
+		return
+	}
 	if err = ex.Sequence.applyFunction(f); err != nil {
 		return
 	}
+	if err = ex.Choice.applyFunction(f); err != nil {
+		return
+	}
+	if err = ex.All.applyFunction(f); err != nil {
+		return
+	}
+	if err = ex.Group.applyFunction(f); err != nil {
+		return
+	}
 	for _, a := range ex.Attributes {
 		if err = a.applyFunction(f); err != nil {
 			return
 		}
 	}
+	for _, ag := range ex.AttributeGroups {
+		if err = ag.applyFunction(f); err != nil {
+			return
+		}
+	}
+	if err = ex.AnyAttribute.applyFunction(f); err != nil {
+		return
+	}
+	for _, as := range ex.Asserts {
+		if err = as.applyFunction(f); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -223,10 +460,78 @@ func (ch *Choice) applyFunction(f func(XsdElement) error) (err error) {
 	if err = f(ch); err != nil {
 		return
 	}
-	if err = ch.Sequence.applyFunction(f); err != nil {
+	if err = ch.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	for _, p := range ch.Particles() {
+		if err = applyFunctionParticle(f, p); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// applyFunction applies a function to a Group definition or reference, a reference doesn't walk the referenced group
+func (g *Group) applyFunction(f func(XsdElement) error) (err error) {
+	if g == nil {
+		return nil
+	}
+	if err = f(g); err != nil {
+		return
+	}
+	if err = g.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	if err = g.Sequence.applyFunction(f); err != nil {
+		return
+	}
+	if err = g.Choice.applyFunction(f); err != nil {
+		return
+	}
+	if err = g.All.applyFunction(f); err != nil {
 		return
 	}
-	for _, e := range ch.Elements {
+	return
+}
+
+// applyFunction applies a function to an AttributeGroup definition or reference, a reference doesn't walk the referenced group
+func (ag *AttributeGroup) applyFunction(f func(XsdElement) error) (err error) {
+	if ag == nil {
+		return nil
+	}
+	if err = f(ag); err != nil {
+		return
+	}
+	if err = ag.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	for _, a := range ag.Attributes {
+		if err = a.applyFunction(f); err != nil {
+			return
+		}
+	}
+	for _, nested := range ag.AttributeGroups {
+		if err = nested.applyFunction(f); err != nil {
+			return
+		}
+	}
+	if err = ag.AnyAttribute.applyFunction(f); err != nil {
+		return
+	}
+	return
+}
+
+func (al *All) applyFunction(f func(XsdElement) error) (err error) {
+	if al == nil {
+		return nil
+	}
+	if err = f(al); err != nil {
+		return
+	}
+	if err = al.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	for _, e := range al.Elements {
 		if err = e.applyFunction(f); err != nil {
 			return
 		}
@@ -234,6 +539,129 @@ func (ch *Choice) applyFunction(f func(XsdElement) error) (err error) {
 	return
 }
 
+func (a *Any) applyFunction(f func(XsdElement) error) (err error) {
+	if a == nil {
+		return nil
+	}
+	if err = f(a); err != nil {
+		return
+	}
+	if err = a.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	return
+}
+
+func (aa *AnyAttribute) applyFunction(f func(XsdElement) error) (err error) {
+	if aa == nil {
+		return nil
+	}
+	if err = f(aa); err != nil {
+		return
+	}
+	if err = aa.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	return
+}
+
+func (u *Unique) applyFunction(f func(XsdElement) error) (err error) {
+	if u == nil {
+		return nil
+	}
+	if err = f(u); err != nil {
+		return
+	}
+	return applyFunctionXPaths(f, u.Selector, u.Fields)
+}
+
+func (k *Key) applyFunction(f func(XsdElement) error) (err error) {
+	if k == nil {
+		return nil
+	}
+	if err = f(k); err != nil {
+		return
+	}
+	return applyFunctionXPaths(f, k.Selector, k.Fields)
+}
+
+func (kr *KeyRef) applyFunction(f func(XsdElement) error) (err error) {
+	if kr == nil {
+		return nil
+	}
+	if err = f(kr); err != nil {
+		return
+	}
+	return applyFunctionXPaths(f, kr.Selector, kr.Fields)
+}
+
+// applyFunctionXPaths applies a function to the selector and fields of an identity constraint
+func applyFunctionXPaths(f func(XsdElement) error, selector *Selector, fields []*Field) (err error) {
+	if selector != nil {
+		if err = f(selector); err != nil {
+			return
+		}
+	}
+	for _, fd := range fields {
+		if err = f(fd); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (as *Assert) applyFunction(f func(XsdElement) error) (err error) {
+	if as == nil {
+		return nil
+	}
+	if err = f(as); err != nil {
+		return
+	}
+	return as.Annotation.applyFunction(f)
+}
+
+func (alt *Alternative) applyFunction(f func(XsdElement) error) (err error) {
+	if alt == nil {
+		return nil
+	}
+	if err = f(alt); err != nil {
+		return
+	}
+	if err = alt.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	if err = alt.ComplexType.applyFunction(f); err != nil {
+		return
+	}
+	return alt.SimpleType.applyFunction(f)
+}
+
+func (oc *OpenContent) applyFunction(f func(XsdElement) error) (err error) {
+	if oc == nil {
+		return nil
+	}
+	if err = f(oc); err != nil {
+		return
+	}
+	if err = oc.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	return oc.Any.applyFunction(f)
+}
+
+func (doc *DefaultOpenContent) applyFunction(f func(XsdElement) error) (err error) {
+	if doc == nil {
+		return nil
+	}
+	if err = f(doc); err != nil {
+		return
+	}
+	if err = doc.Annotation.applyFunction(f); err != nil {
+		return
+	}
+	return doc.Any.applyFunction(f)
+}
+
 func (a *Annotation) applyFunction(f func(XsdElement) error) (err error) {
 	if a == nil {
 		return nil
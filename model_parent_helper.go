@@ -13,9 +13,34 @@ func (xsd *XSD) ApplyFunctionP(f func(XsdElement, interface{}) (interface{}, err
 	if child, err = f(xsd, nil); err != nil {
 		return
 	}
-	if _, err = xsd.Import.applyFunctionP(f, child); err != nil {
+	for _, a := range xsd.Annotations {
+		if _, err = a.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	if _, err = xsd.DefaultOpenContent.applyFunctionP(f, child); err != nil {
 		return
 	}
+	for _, i := range xsd.Imports {
+		if _, err = i.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, i := range xsd.Includes {
+		if _, err = i.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, r := range xsd.Redefines {
+		if _, err = r.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, o := range xsd.Overrides {
+		if _, err = o.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
 	for _, ct := range xsd.ComplexTypes {
 		if _, err = ct.applyFunctionP(f, child); err != nil {
 			return
@@ -31,6 +56,21 @@ func (xsd *XSD) ApplyFunctionP(f func(XsdElement, interface{}) (interface{}, err
 			return
 		}
 	}
+	for _, a := range xsd.Attributes {
+		if _, err = a.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, g := range xsd.Groups {
+		if _, err = g.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, ag := range xsd.AttributeGroups {
+		if _, err = ag.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
 
 	return
 }
@@ -43,6 +83,30 @@ func (i *Import) applyFunctionP(f func(XsdElement, interface{}) (interface{}, er
 	return f(i, parent)
 }
 
+// applyFunctionP applies a function to Include as long as function returns true
+func (i *Include) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if i == nil {
+		return true, nil
+	}
+	return f(i, parent)
+}
+
+// applyFunctionP applies a function to Redefine, the redefined types are walked as part of the combined schema
+func (r *Redefine) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if r == nil {
+		return true, nil
+	}
+	return f(r, parent)
+}
+
+// applyFunctionP applies a function to Override, the overriding components are walked as part of the combined schema
+func (o *Override) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if o == nil {
+		return true, nil
+	}
+	return f(o, parent)
+}
+
 // applyFunctionP applies a function to ComplexType and children as long as function returns true
 func (ct *ComplexType) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
 	if ct == nil {
@@ -51,6 +115,12 @@ func (ct *ComplexType) applyFunctionP(f func(XsdElement, interface{}) (interface
 	if child, err = f(ct, parent); err != nil {
 		return
 	}
+	if _, err = ct.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = ct.OpenContent.applyFunctionP(f, child); err != nil {
+		return
+	}
 	if _, err = ct.Sequence.applyFunctionP(f, child); err != nil {
 		return
 	}
@@ -60,6 +130,9 @@ func (ct *ComplexType) applyFunctionP(f func(XsdElement, interface{}) (interface
 	if _, err = ct.SimpleContent.applyFunctionP(f, child); err != nil {
 		return
 	}
+	if _, err = ct.Group.applyFunctionP(f, child); err != nil {
+		return
+	}
 	for _, a := range ct.Attributes {
 		if _, err = a.applyFunctionP(f, child); err != nil {
 			return
@@ -68,6 +141,22 @@ func (ct *ComplexType) applyFunctionP(f func(XsdElement, interface{}) (interface
 	if _, err = ct.Choice.applyFunctionP(f, child); err != nil {
 		return
 	}
+	if _, err = ct.All.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, ag := range ct.AttributeGroups {
+		if _, err = ag.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	if _, err = ct.AnyAttribute.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, as := range ct.Asserts {
+		if _, err = as.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -79,9 +168,54 @@ func (st *SimpleType) applyFunctionP(f func(XsdElement, interface{}) (interface{
 	if child, err = f(st, parent); err != nil {
 		return
 	}
+	if _, err = st.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
 	if _, err = st.Restriction.applyFunctionP(f, child); err != nil {
 		return
 	}
+	if _, err = st.List.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = st.Union.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
+}
+
+// applyFunctionP applies a function to List and its inline SimpleType as long as function returns true
+func (l *List) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if l == nil {
+		return true, nil
+	}
+	if child, err = f(l, parent); err != nil {
+		return
+	}
+	if _, err = l.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = l.SimpleType.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
+}
+
+// applyFunctionP applies a function to Union and its inline SimpleTypes as long as function returns true
+func (u *Union) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if u == nil {
+		return true, nil
+	}
+	if child, err = f(u, parent); err != nil {
+		return
+	}
+	if _, err = u.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, st := range u.SimpleTypes {
+		if _, err = st.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -93,99 +227,178 @@ func (r *Restriction) applyFunctionP(f func(XsdElement, interface{}) (interface{
 	if child, err = f(r, parent); err != nil {
 		return
 	}
+	if _, err = r.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
 	for _, e := range r.Enumerations {
 		if _, err = e.applyFunctionP(f, child); err != nil {
 			return
 		}
 	}
-	if _, err = r.Pattern.applyFunctionP(f, child); err != nil {
+	for _, facet := range r.facets() {
+		if _, err = f(facet, child); err != nil {
+			return
+		}
+	}
+	if _, err = r.SimpleType.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = r.OpenContent.applyFunctionP(f, child); err != nil {
 		return
 	}
-	if _, err = r.MinInclusive.applyFunctionP(f, child); err != nil {
+	if _, err = r.Sequence.applyFunctionP(f, child); err != nil {
 		return
 	}
-	if _, err = r.MaxInclusive.applyFunctionP(f, child); err != nil {
+	if _, err = r.Choice.applyFunctionP(f, child); err != nil {
 		return
 	}
+	if _, err = r.All.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = r.Group.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, a := range r.Attributes {
+		if _, err = a.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, ag := range r.AttributeGroups {
+		if _, err = ag.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	if _, err = r.AnyAttribute.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, as := range r.Asserts {
+		if _, err = as.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
 	return
 }
 
-// applyFunctionP applies a function Pattern and children as long as function returns true
-func (p *Pattern) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
-	if p == nil {
+// applyFunctionP applies a function Enumeration and children as long as function returns true
+func (e *Enumeration) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if e == nil {
 		return true, nil
 	}
-	if child, err = f(p, parent); err != nil {
+	if child, err = f(e, parent); err != nil {
+		return
+	}
+	if _, err = e.Annotation.applyFunctionP(f, child); err != nil {
 		return
 	}
 	return
 }
 
-// applyFunctionP applies a function MinInclusive and children as long as function returns true
-func (mi *MinInclusive) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
-	if mi == nil {
+// applyFunctionP applies a function to Sequence and children as long as function returns true
+func (s *Sequence) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if s == nil {
 		return true, nil
 	}
-	if child, err = f(mi, parent); err != nil {
+	if child, err = f(s, parent); err != nil {
+		return
+	}
+	if _, err = s.Annotation.applyFunctionP(f, child); err != nil {
 		return
 	}
+	for _, p := range s.Particles() {
+		if _, err = applyFunctionPParticle(f, p, child); err != nil {
+			return
+		}
+	}
 	return
 }
 
-// applyFunctionP applies a function MaxInclusive and children as long as function returns true
-func (mi *MaxInclusive) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
-	if mi == nil {
+// applyFunctionP applies a function to Choice and children as long as function returns true
+func (c *Choice) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if c == nil {
 		return true, nil
 	}
-	if child, err = f(mi, parent); err != nil {
+	if child, err = f(c, parent); err != nil {
+		return
+	}
+	if _, err = c.Annotation.applyFunctionP(f, child); err != nil {
 		return
 	}
+	for _, p := range c.Particles() {
+		if _, err = applyFunctionPParticle(f, p, child); err != nil {
+			return
+		}
+	}
 	return
 }
 
-// applyFunctionP applies a function Enumeration and children as long as function returns true
-func (e *Enumeration) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
-	if e == nil {
+// applyFunctionP applies a function to a Group and children as long as function returns true.
+// A group reference doesn't walk the referenced group
+func (g *Group) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if g == nil {
 		return true, nil
 	}
-	return f(e, parent)
+	if child, err = f(g, parent); err != nil {
+		return
+	}
+	if _, err = g.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = g.Sequence.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = g.Choice.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = g.All.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
 }
 
-// applyFunctionP applies a function to Sequence and children as long as function returns true
-func (s *Sequence) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
-	if s == nil {
+// applyFunctionP applies a function to an AttributeGroup and children as long as function returns true.
+// An attribute group reference doesn't walk the referenced group
+func (ag *AttributeGroup) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if ag == nil {
 		return true, nil
 	}
-	if child, err = f(s, parent); err != nil {
+	if child, err = f(ag, parent); err != nil {
 		return
 	}
-	for _, elm := range s.Elements {
-		if _, err = elm.applyFunctionP(f, child); err != nil {
+	if _, err = ag.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, a := range ag.Attributes {
+		if _, err = a.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, nested := range ag.AttributeGroups {
+		if _, err = nested.applyFunctionP(f, child); err != nil {
 			return
 		}
 	}
-	if _, err = s.Choice.applyFunctionP(f, child); err != nil {
+	if _, err = ag.AnyAttribute.applyFunctionP(f, child); err != nil {
 		return
 	}
 	return
 }
 
-// applyFunctionP applies a function to Choice and children as long as function returns true
-func (c *Choice) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
-	if c == nil {
+// applyFunctionP applies a function to All and children as long as function returns true
+func (al *All) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if al == nil {
 		return true, nil
 	}
-	if child, err = f(c, parent); err != nil {
+	if child, err = f(al, parent); err != nil {
 		return
 	}
-	for _, elm := range c.Elements {
+	if _, err = al.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, elm := range al.Elements {
 		if _, err = elm.applyFunctionP(f, child); err != nil {
 			return
 		}
 	}
-	if _, err = c.Sequence.applyFunctionP(f, child); err != nil {
-		return
-	}
 	return
 }
 
@@ -203,9 +416,29 @@ func (e *Element) applyFunctionP(f func(XsdElement, interface{}) (interface{}, e
 	if _, err = e.SimpleType.applyFunctionP(f, child); err != nil {
 		return
 	}
+	for _, alt := range e.Alternatives {
+		if _, err = alt.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
 	if _, err = e.Annotation.applyFunctionP(f, child); err != nil {
 		return
 	}
+	for _, u := range e.Uniques {
+		if _, err = u.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, k := range e.Keys {
+		if _, err = k.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, kr := range e.KeyRefs {
+		if _, err = kr.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
 	return
 }
 
@@ -217,9 +450,15 @@ func (cc *ComplexContent) applyFunctionP(f func(XsdElement, interface{}) (interf
 	if child, err = f(cc, parent); err != nil {
 		return
 	}
+	if _, err = cc.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
 	if _, err = cc.Extension.applyFunctionP(f, child); err != nil {
 		return
 	}
+	if _, err = cc.Restriction.applyFunctionP(f, child); err != nil {
+		return
+	}
 	return
 }
 
@@ -231,9 +470,15 @@ func (sc *SimpleContent) applyFunctionP(f func(XsdElement, interface{}) (interfa
 	if child, err = f(sc, parent); err != nil {
 		return
 	}
+	if _, err = sc.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
 	if _, err = sc.Extension.applyFunctionP(f, child); err != nil {
 		return
 	}
+	if _, err = sc.Restriction.applyFunctionP(f, child); err != nil {
+		return
+	}
 	return
 }
 
@@ -245,11 +490,39 @@ func (ex *Extension) applyFunctionP(f func(XsdElement, interface{}) (interface{}
 	if child, err = f(ex, parent); err != nil {
 		return
 	}
+	if _, err = ex.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = ex.OpenContent.applyFunctionP(f, child); err != nil {
+		return
+	}
 	if _, err = ex.Sequence.applyFunctionP(f, child); err != nil {
 		return
 	}
+	if _, err = ex.Choice.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = ex.All.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = ex.Group.applyFunctionP(f, child); err != nil {
+		return
+	}
 	for _, a := range ex.Attributes {
-		if child, err = a.applyFunctionP(f, child); err != nil {
+		if _, err = a.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	for _, ag := range ex.AttributeGroups {
+		if _, err = ag.applyFunctionP(f, child); err != nil {
+			return
+		}
+	}
+	if _, err = ex.AnyAttribute.applyFunctionP(f, child); err != nil {
+		return
+	}
+	for _, as := range ex.Asserts {
+		if _, err = as.applyFunctionP(f, child); err != nil {
 			return
 		}
 	}
@@ -276,6 +549,151 @@ func (a *Attribute) applyFunctionP(f func(XsdElement, interface{}) (interface{},
 	return
 }
 
+// applyFunctionP applies a function to the Any wildcard
+func (a *Any) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if a == nil {
+		return true, nil
+	}
+	if child, err = f(a, parent); err != nil {
+		return
+	}
+	if _, err = a.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
+}
+
+// applyFunctionP applies a function to the AnyAttribute wildcard
+func (aa *AnyAttribute) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if aa == nil {
+		return true, nil
+	}
+	if child, err = f(aa, parent); err != nil {
+		return
+	}
+	if _, err = aa.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
+}
+
+// applyFunctionP applies a function to Unique and its selector and fields
+func (u *Unique) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if u == nil {
+		return true, nil
+	}
+	if child, err = f(u, parent); err != nil {
+		return
+	}
+	err = applyFunctionPXPaths(f, child, u.Selector, u.Fields)
+	return
+}
+
+// applyFunctionP applies a function to Key and its selector and fields
+func (k *Key) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if k == nil {
+		return true, nil
+	}
+	if child, err = f(k, parent); err != nil {
+		return
+	}
+	err = applyFunctionPXPaths(f, child, k.Selector, k.Fields)
+	return
+}
+
+// applyFunctionP applies a function to KeyRef and its selector and fields
+func (kr *KeyRef) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if kr == nil {
+		return true, nil
+	}
+	if child, err = f(kr, parent); err != nil {
+		return
+	}
+	err = applyFunctionPXPaths(f, child, kr.Selector, kr.Fields)
+	return
+}
+
+// applyFunctionPXPaths applies a function to the selector and fields of an identity constraint
+func applyFunctionPXPaths(f func(XsdElement, interface{}) (interface{}, error), parent interface{}, selector *Selector, fields []*Field) (err error) {
+	if selector != nil {
+		if _, err = f(selector, parent); err != nil {
+			return
+		}
+	}
+	for _, fd := range fields {
+		if _, err = f(fd, parent); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// applyFunctionP applies a function to Assert and its annotation
+func (as *Assert) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if as == nil {
+		return true, nil
+	}
+	if child, err = f(as, parent); err != nil {
+		return
+	}
+	_, err = as.Annotation.applyFunctionP(f, child)
+	return
+}
+
+// applyFunctionP applies a function to Alternative and its type
+func (alt *Alternative) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if alt == nil {
+		return true, nil
+	}
+	if child, err = f(alt, parent); err != nil {
+		return
+	}
+	if _, err = alt.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = alt.ComplexType.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = alt.SimpleType.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
+}
+
+// applyFunctionP applies a function to OpenContent and its wildcard
+func (oc *OpenContent) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if oc == nil {
+		return true, nil
+	}
+	if child, err = f(oc, parent); err != nil {
+		return
+	}
+	if _, err = oc.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = oc.Any.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
+}
+
+// applyFunctionP applies a function to DefaultOpenContent and its wildcard
+func (doc *DefaultOpenContent) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
+	if doc == nil {
+		return true, nil
+	}
+	if child, err = f(doc, parent); err != nil {
+		return
+	}
+	if _, err = doc.Annotation.applyFunctionP(f, child); err != nil {
+		return
+	}
+	if _, err = doc.Any.applyFunctionP(f, child); err != nil {
+		return
+	}
+	return
+}
+
 // applyFunctionP applies a function to Annotation and children as long as function returns true
 func (a *Annotation) applyFunctionP(f func(XsdElement, interface{}) (interface{}, error), parent interface{}) (child interface{}, err error) {
 	if a == nil {
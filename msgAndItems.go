@@ -3,33 +3,57 @@ package xsd
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 )
 
 type Message struct {
-	Package       string         `json:"package,omitempty"`
-	Name          string         `json:"name,omitempty"`
-	MessageItems  []*MessageItem `json:"messageItems,omitempty"`
-	Description   string         `json:"description,omitempty"`
-	IsRootMessage bool           `json:"isNamed,omitempty"` // If set to true then this is a root level message and not a sub message
+	Package       string            `json:"package,omitempty"`
+	Name          string            `json:"name,omitempty"`
+	MessageItems  []*MessageItem    `json:"messageItems,omitempty"`
+	Description   string            `json:"description,omitempty"`
+	Descriptions  map[string]string `json:"descriptions,omitempty"` // The description by language, when the documentation has xml:lang
+	Mixed         bool              `json:"mixed,omitempty"`        // Text can appear between the message items, e.g. a narrative document
+	Assertions    []string          `json:"assertions,omitempty"`   // XPath tests of xs:assert the message has to pass
+	IsRootMessage bool              `json:"isNamed,omitempty"`      // If set to true then this is a root level message and not a sub message
 	sequence      int
 }
 
 type MessageItem struct {
-	Name              string   `json:"name,omitempty"`
-	Type              string   `json:"type,omitempty"`
-	Format            string   `json:"format,omitempty"`
-	Repeated          bool     `json:"repeated,omitempty"`
-	MandatoryOptional string   `json:"mandatoryOptional,omitempty"` // M=Mandatory,O=Optional, blank=don't know
-	MinOccurs         string   `json:"minOccurs,omitempty"`
-	MaxOccurs         string   `json:"maxOccurs,omitempty"`
-	MinLength         int      `json:"minLength,omitempty"`
-	MaxLength         int      `json:"maxLength,omitempty"`
-	Description       string   `json:"description,omitempty"`
-	Values            []string `json:"values,omitempty"`
-	Pattern           string   `json:"pattern"`
-	MinInclusive      string   `json:"minInclusive,omitempty"`
-	MaxInclusive      string   `json:"maxInclusive,omitempty"`
+	Name              string            `json:"name,omitempty"`
+	Type              string            `json:"type,omitempty"`
+	Format            string            `json:"format,omitempty"`
+	Repeated          bool              `json:"repeated,omitempty"`
+	MandatoryOptional string            `json:"mandatoryOptional,omitempty"` // M=Mandatory,O=Optional, blank=don't know
+	MinOccurs         string            `json:"minOccurs,omitempty"`
+	MaxOccurs         string            `json:"maxOccurs,omitempty"`
+	MinLength         int               `json:"minLength,omitempty"`
+	MaxLength         int               `json:"maxLength,omitempty"`
+	Description       string            `json:"description,omitempty"`
+	Values            []string          `json:"values,omitempty"`
+	Pattern           string            `json:"pattern"` // The first pattern, see Patterns
+	MinInclusive      string            `json:"minInclusive,omitempty"`
+	MaxInclusive      string            `json:"maxInclusive,omitempty"`
+	MinExclusive      string            `json:"minExclusive,omitempty"`
+	MaxExclusive      string            `json:"maxExclusive,omitempty"`
+	Length            int               `json:"length,omitempty"`
+	TotalDigits       int               `json:"totalDigits,omitempty"`
+	FractionDigits    int               `json:"fractionDigits,omitempty"`
+	WhiteSpace        string            `json:"whiteSpace,omitempty"`
+	Patterns          []string          `json:"patterns,omitempty"`        // All the patterns, a value matches one of them
+	MemberTypes       []string          `json:"memberTypes,omitempty"`     // The alternative types of a union
+	Wildcard          bool              `json:"wildcard,omitempty"`        // The item is an xs:any or xs:anyAttribute
+	Namespace         string            `json:"namespace,omitempty"`       // The namespaces allowed by a wildcard
+	ProcessContents   string            `json:"processContents,omitempty"` // How a wildcard's content is validated
+	Default           string            `json:"default,omitempty"`
+	Fixed             string            `json:"fixed,omitempty"`
+	Nillable          bool              `json:"nillable,omitempty"`
+	Abstract          bool              `json:"abstract,omitempty"`          // Only members of the element's substitution group can be used
+	OneOf             []*MessageItem    `json:"oneOf,omitempty"`             // The elements of a substitution group, one of which is used in place of the head
+	Descriptions      map[string]string `json:"descriptions,omitempty"`      // The description by language, when the documentation has xml:lang
+	ValueDescriptions map[string]string `json:"valueDescriptions,omitempty"` // The description of each enumeration value that has one
+	Assertions        []string          `json:"assertions,omitempty"`        // XPath tests of xs:assertion facets the value has to pass, the value is $value
+	Recursive         bool              `json:"recursive,omitempty"`         // The item's type leads back to this message so generated code needs a pointer or optional wrapper
 }
 
 type tf struct {
@@ -40,25 +64,119 @@ type tf struct {
 	values       []string // Example values
 }
 
-var xsdTransMap map[string]tf
+var xsdTransMap map[string]tf // Keyed by the local name of the XML Schema built-in type
+
+// MessagesOption changes how Messages builds the messages
+type MessagesOption func(*messagesOptions)
+
+type messagesOptions struct {
+	flatten bool
+}
+
+// Flatten gives the message of a derived complex type the items of its base types, see EffectiveContent,
+// rather than an item of the base type
+func Flatten() MessagesOption {
+	return func(o *messagesOptions) {
+		o.flatten = true
+	}
+}
 
 // Messages returns messages and message items (protobuf style)
 // Could also align to json schema some time
 // fmtStd is the format standard can be "protobuf" or "json"
-func (xsd *XSD) Messages(fmtStd string) (messages []*Message, err error) {
+func (xsd *XSD) Messages(fmtStd string, opts ...MessagesOption) (messages []*Message, err error) {
+	var options messagesOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	buildXsdTransMap(fmtStd)
 	messageMap := make(map[string]*Message)
-	fDisplay := func(xe XsdElement, h interface{}) (interface{}, error) {
+	setType := func(mi *MessageItem, t string, qn QName) {
+		mi.setTypeOrMessage(t, qn, xsd.isLocalName(qn), messageMap)
+	}
+	skipped := &Message{}                              // Returned for parts of the schema that don't make messages themselves, e.g. group definitions
+	expanding := make(map[XsdElement]bool)             // Group definitions being expanded, a group can't contain itself
+	complexRestrictions := make(map[*Restriction]bool) // Restrictions of complex content list the restricted content
+	var fDisplay func(xe XsdElement, h interface{}) (interface{}, error)
+	// flattenContent adds the items of a base type's content model to the message, leaving out replaced attributes
+	flattenContent := func(cm *ContentModel, replaced []*Attribute, msg *Message) error {
+		names := make(map[QName]bool)
+		for _, a := range replaced {
+			names[a.QName()] = true
+		}
+		for _, p := range cm.Particles {
+			if _, err := applyFunctionPParticle(fDisplay, p, msg); err != nil {
+				return err
+			}
+		}
+		for _, a := range cm.Attributes {
+			if names[a.QName()] {
+				continue
+			}
+			if _, err := a.applyFunctionP(fDisplay, msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	fDisplay = func(xe XsdElement, h interface{}) (interface{}, error) {
 		var currentMsg *Message
 		if h != nil {
 			currentMsg = h.(*Message)
 		}
+		if currentMsg == skipped {
+			return skipped, nil
+		}
 		// In general,we're only interested in complex types and elements
 		switch t := xe.(type) {
+		case *Group: // A group reference brings the content of the group definition into the current message
+			if t.Ref == "" || currentMsg == nil {
+				return skipped, nil // The content is added where the group is referenced
+			}
+			def := t.RefGroup
+			if def == nil || expanding[def] {
+				return currentMsg, nil
+			}
+			expanding[def] = true
+			defer delete(expanding, def)
+			if _, err := def.Sequence.applyFunctionP(fDisplay, currentMsg); err != nil {
+				return currentMsg, err
+			}
+			if _, err := def.Choice.applyFunctionP(fDisplay, currentMsg); err != nil {
+				return currentMsg, err
+			}
+			if _, err := def.All.applyFunctionP(fDisplay, currentMsg); err != nil {
+				return currentMsg, err
+			}
+
+		case *AttributeGroup: // An attribute group reference brings the attributes into the current message
+			if t.Ref == "" || currentMsg == nil {
+				return skipped, nil
+			}
+			def := t.RefAttributeGroup
+			if def == nil || expanding[def] {
+				return currentMsg, nil
+			}
+			expanding[def] = true
+			defer delete(expanding, def)
+			for _, a := range def.Attributes {
+				if _, err := a.applyFunctionP(fDisplay, currentMsg); err != nil {
+					return currentMsg, err
+				}
+			}
+			for _, ag := range def.AttributeGroups {
+				if _, err := ag.applyFunctionP(fDisplay, currentMsg); err != nil {
+					return currentMsg, err
+				}
+			}
+
 		case *ComplexType:
-			msg := &Message{sequence: len(messageMap)}
+			msg := &Message{sequence: len(messageMap), Mixed: t.IsMixed()}
 			if t.Name == "" { // If Complex Type doesn't have a name then element Name is the type
 				if currentMsg == nil || len(currentMsg.MessageItems) == 0 {
+					if currentMsg != nil { // The type of a global element, the element's message is the type
+						currentMsg.Mixed = t.IsMixed()
+					}
 					return currentMsg, nil
 				}
 				msg.Name = currentMsg.MessageItems[len(currentMsg.MessageItems)-1].Name
@@ -69,6 +187,7 @@ func (xsd *XSD) Messages(fmtStd string) (messages []*Message, err error) {
 			if _, inMap := messageMap[msg.Name]; !inMap {
 				messageMap[msg.Name] = msg
 				currentMsg = msg // Now the elements we come across belong to this message
+				currentMsg.describe(t.Annotation)
 			}
 		case *SimpleType:
 			if t.Name == "" {
@@ -87,19 +206,32 @@ func (xsd *XSD) Messages(fmtStd string) (messages []*Message, err error) {
 				msg.Name = t.Name
 				msg.IsRootMessage = currentMsg == nil
 			}
-			if _, inMap := messageMap[msg.Name]; !inMap {
-				messageMap[msg.Name] = msg
-				currentMsg = msg // Now the elements we come across belong to this message
+			if _, inMap := messageMap[msg.Name]; inMap {
+				return skipped, nil // A type of the same name in another namespace of the schema is the same message
 			}
+			messageMap[msg.Name] = msg
+			currentMsg = msg // Now the elements we come across belong to this message
+			currentMsg.describe(t.Annotation)
 		case *Attribute: // Attributes are added to a message
+			if t.Use == "prohibited" {
+				return skipped, nil // A restriction removing the attribute of its base type
+			}
 			if currentMsg == nil {
-				return currentMsg, fmt.Errorf("attribute but no current message")
+				return skipped, nil // A global attribute is an item where it's referenced, not a message
 			}
-			mi := &MessageItem{Name: t.Name, Repeated: false, MandatoryOptional: t.IsMandatoryOptional()}
+			mi := &MessageItem{Name: t.Name, Repeated: false, MandatoryOptional: t.IsMandatoryOptional(), Default: t.Default, Fixed: t.Fixed}
+			if t.RefAttribute != nil {
+				if t.Default == "" && t.Fixed == "" { // The global attribute's value applies unless the reference has its own
+					mi.Default, mi.Fixed = t.RefAttribute.Default, t.RefAttribute.Fixed
+				}
+				mi.describe(t.RefAttribute.Annotation)
+			}
+			mi.describe(t.Annotation)
 			if t.Ref > "" {
-				mi.Name, mi.Type = t.Ref, t.Ref
+				mi.Name = t.RefQName.Local
+				setType(mi, t.Ref, t.RefQName)
 			} else {
-				mi.setTypeOrMessage(mi.Type, messageMap)
+				setType(mi, t.Type, t.TypeQName)
 			}
 			currentMsg.MessageItems = append(currentMsg.MessageItems, mi)
 
@@ -107,22 +239,161 @@ func (xsd *XSD) Messages(fmtStd string) (messages []*Message, err error) {
 			if currentMsg == nil {
 				return currentMsg, fmt.Errorf("extension but no current message")
 			}
+			if base, isComplex := xsd.symbolTable().types[t.BaseQName].(*ComplexType); isComplex && options.flatten {
+				cm, err := xsd.EffectiveContent(base)
+				if err != nil {
+					return currentMsg, err
+				}
+				if cm.SimpleType.Local > "" { // The text of simple content
+					mi := &MessageItem{Name: currentMsg.Name}
+					setType(mi, cm.SimpleType.Local, cm.SimpleType)
+					currentMsg.MessageItems = append(currentMsg.MessageItems, mi)
+				}
+				if err = flattenContent(cm, nil, currentMsg); err != nil {
+					return currentMsg, err
+				}
+				break // The extension's own content follows
+			}
 			mi := &MessageItem{Name: currentMsg.Name, Repeated: false}
-			mi.setTypeOrMessage(t.Base, messageMap)
+			setType(mi, t.Base, t.BaseQName)
 			currentMsg.MessageItems = append(currentMsg.MessageItems, mi)
 
+		case *ComplexContent:
+			if t.Restriction != nil {
+				complexRestrictions[t.Restriction] = true
+			}
+
 		case *Restriction: // Restriction provides more information about the current message item
 			if currentMsg == nil {
 				return currentMsg, fmt.Errorf("restriction but no current message")
 			}
+			// Restricted complex content replaces the content of the base type but keeps the attributes it doesn't redeclare
+			if base, isComplex := xsd.symbolTable().types[t.BaseQName].(*ComplexType); isComplex && (options.flatten || complexRestrictions[t]) {
+				cm, err := xsd.EffectiveContent(base)
+				if err != nil {
+					return currentMsg, err
+				}
+				// The restriction's own content follows, base attributes first so facets apply to the text item
+				if err = flattenContent(&ContentModel{Attributes: cm.Attributes}, t.Attributes, currentMsg); err != nil {
+					return currentMsg, err
+				}
+				if complexRestrictions[t] {
+					break
+				}
+				mi := &MessageItem{Name: currentMsg.Name}
+				setType(mi, cm.SimpleType.Local, cm.SimpleType)
+				currentMsg.MessageItems = append(currentMsg.MessageItems, mi)
+				break
+			}
 			// Create a message item if we haven't already
 			if len(currentMsg.MessageItems) == 0 {
 				mi := &MessageItem{Name: currentMsg.Name}
-				mi.setTypeOrMessage(t.Base, messageMap)
+				setType(mi, t.Base, t.BaseQName)
 				currentMsg.MessageItems = append(currentMsg.MessageItems, mi)
 			} else {
-				currentMsg.MessageItems[len(currentMsg.MessageItems)-1].setTypeOrMessage(t.Base, messageMap)
+				setType(currentMsg.MessageItems[len(currentMsg.MessageItems)-1], t.Base, t.BaseQName)
+			}
+
+		case *List: // A list is a repeated item of the item type
+			if currentMsg == nil {
+				return currentMsg, fmt.Errorf("list but no current message")
+			}
+			mi := simpleTypeItem(currentMsg)
+			mi.Repeated = true
+			if t.ItemType > "" {
+				setType(mi, t.ItemType, t.ItemTypeQName)
+			} // Otherwise the restriction of the inline simple type sets the type
+
+		case *Union: // A union records the alternative types of the item
+			if currentMsg == nil {
+				return currentMsg, fmt.Errorf("union but no current message")
+			}
+			mi := simpleTypeItem(currentMsg)
+			mi.MemberTypes = nil
+			for i, m := range strings.Fields(t.MemberTypes) {
+				member := &MessageItem{Name: mi.Name}
+				if i < len(t.MemberTypeQNames) {
+					setType(member, m, t.MemberTypeQNames[i])
+				}
+				mi.MemberTypes = append(mi.MemberTypes, member.Type)
+			}
+			for _, st := range t.SimpleTypes {
+				member := &MessageItem{Name: mi.Name}
+				switch {
+				case st.Restriction != nil:
+					setType(member, st.Restriction.Base, st.Restriction.BaseQName)
+					for _, e := range st.Restriction.Enumerations {
+						mi.Values = append(mi.Values, e.Value)
+					}
+				case st.List != nil && st.List.ItemType > "":
+					setType(member, st.List.ItemType, st.List.ItemTypeQName)
+				}
+				mi.MemberTypes = append(mi.MemberTypes, member.Type)
+			}
+			// The item takes the member type if they are all the same, otherwise it can only be a string
+			mi.Type = ""
+			for _, m := range mi.MemberTypes {
+				if mi.Type == "" {
+					mi.Type = m
+				} else if mi.Type != m {
+					mi.setTypeOrMessage("string", QName{Namespace: XMLSchemaNamespace, Local: "string"}, true, messageMap)
+					break
+				}
+			}
+			return skipped, nil // The inline simple types have been dealt with
+
+		case *DefaultOpenContent: // Open content is only shown where a complex type has its own
+			return skipped, nil
+
+		case *OpenContent: // Open content allows the wildcard's elements as well as the content model
+			if t.Mode == "none" {
+				return skipped, nil
+			}
+
+		case *Alternative: // The types of alternatives are only known once the element is in a document
+			return skipped, nil
+
+		case *Assert:
+			if currentMsg == nil {
+				return currentMsg, fmt.Errorf("assert but no current message")
+			}
+			currentMsg.Assertions = append(currentMsg.Assertions, t.Test)
+
+		case *Assertion:
+			mi, err := currentItem(currentMsg, "assertion")
+			if err != nil {
+				return currentMsg, err
+			}
+			mi.Assertions = append(mi.Assertions, t.Test)
+
+		case *Any: // Any element is allowed, the item holds whatever is found
+			if currentMsg == nil {
+				return currentMsg, fmt.Errorf("any but no current message")
+			}
+			currentMsg.MessageItems = append(currentMsg.MessageItems, &MessageItem{
+				Name:              "any",
+				Type:              wildcardType(fmtStd, false),
+				Repeated:          t.IsRepeated(),
+				MandatoryOptional: t.IsMandatoryOptional(),
+				MinOccurs:         t.MinOccurs,
+				MaxOccurs:         t.MaxOccurs,
+				Wildcard:          true,
+				Namespace:         t.Namespace,
+				ProcessContents:   t.ProcessContents,
+			})
+
+		case *AnyAttribute: // Any attribute is allowed, the item holds the attributes by name
+			if currentMsg == nil {
+				return currentMsg, fmt.Errorf("anyAttribute but no current message")
 			}
+			currentMsg.MessageItems = append(currentMsg.MessageItems, &MessageItem{
+				Name:              "anyAttribute",
+				Type:              wildcardType(fmtStd, true),
+				MandatoryOptional: "O",
+				Wildcard:          true,
+				Namespace:         t.Namespace,
+				ProcessContents:   t.ProcessContents,
+			})
 
 		case *Element:
 			if currentMsg == nil {
@@ -131,74 +402,154 @@ func (xsd *XSD) Messages(fmtStd string) (messages []*Message, err error) {
 					messageMap[currentMsg.Name] = currentMsg
 				}
 				if t.Type == "" {
+					currentMsg.describe(t.Annotation)
 					return currentMsg, nil // Let something else create the message item
 				}
 			}
+			d := declaration(t) // A reference takes these from the global element
 			mi := &MessageItem{
 				Name:              t.Name,
 				Repeated:          t.IsRepeated(),
 				MandatoryOptional: t.IsMandatoryOptional(),
 				MinOccurs:         t.MinOccurs,
 				MaxOccurs:         t.MaxOccurs,
+				Default:           d.Default,
+				Fixed:             d.Fixed,
+				Nillable:          d.Nillable,
+				Abstract:          d.Abstract,
+			}
+			mi.describe(d.Annotation)
+			if d != t {
+				mi.describe(t.Annotation) // The reference's own documentation takes precedence
 			}
 			if t.Ref > "" {
-				mi.Name, mi.Type = t.Ref, t.Ref
+				mi.Name = t.RefQName.Local
+				setType(mi, t.Ref, t.RefQName)
+				// The head of a substitution group is a choice of the head and its members
+				if members := xsd.SubstitutionGroupMembers(d); len(members) > 0 {
+					if !d.Abstract {
+						mi.OneOf = append(mi.OneOf, &MessageItem{Name: mi.Name, Type: mi.Type})
+					}
+					for _, m := range members {
+						member := &MessageItem{Name: m.Name}
+						setType(member, m.Name, QName{Namespace: m.Namespace, Local: m.Name})
+						mi.OneOf = append(mi.OneOf, member)
+					}
+				}
 			} else {
-				mi.setTypeOrMessage(t.Type, messageMap)
+				setType(mi, t.Type, t.TypeQName)
 			}
 			currentMsg.MessageItems = append(currentMsg.MessageItems, mi)
 
-		case *Annotation:
-			if currentMsg == nil {
-				return currentMsg, fmt.Errorf("annotation but no current message")
+		case *Pattern:
+			mi, err := currentItem(currentMsg, "pattern")
+			if err != nil {
+				return currentMsg, err
 			}
-			if len(currentMsg.MessageItems) == 0 {
-				currentMsg.Description = t.Documentation
-			} else {
-				currentMsg.MessageItems[len(currentMsg.MessageItems)-1].Description = t.Documentation
+			if mi.Patterns = append(mi.Patterns, t.Value); len(mi.Patterns) == 1 {
+				mi.Pattern, mi.Format = t.Value, t.Value // Pattern and Format have the first pattern, Patterns has them all
 			}
 
-		case *Pattern:
-			if currentMsg == nil {
-				return currentMsg, fmt.Errorf("pattern but no current message")
+		case *MinInclusive:
+			mi, err := currentItem(currentMsg, "minInclusive")
+			if err != nil {
+				return currentMsg, err
 			}
-			if len(currentMsg.MessageItems) == 0 {
-				return currentMsg, fmt.Errorf("pattern but no current message item")
-			} else {
-				currentMsg.MessageItems[len(currentMsg.MessageItems)-1].Format = t.Value
+			mi.MinInclusive = t.Value
+
+		case *MaxInclusive:
+			mi, err := currentItem(currentMsg, "maxInclusive")
+			if err != nil {
+				return currentMsg, err
 			}
+			mi.MaxInclusive = t.Value
 
-		case *MinInclusive:
-			if currentMsg == nil {
-				return currentMsg, fmt.Errorf("minInclusive but no current message")
+		case *MinExclusive:
+			mi, err := currentItem(currentMsg, "minExclusive")
+			if err != nil {
+				return currentMsg, err
 			}
-			if len(currentMsg.MessageItems) == 0 {
-				return currentMsg, fmt.Errorf("minInclusive but no current message item")
-			} else {
-				currentMsg.MessageItems[len(currentMsg.MessageItems)-1].MinInclusive = t.Value
+			mi.MinExclusive = t.Value
+
+		case *MaxExclusive:
+			mi, err := currentItem(currentMsg, "maxExclusive")
+			if err != nil {
+				return currentMsg, err
 			}
+			mi.MaxExclusive = t.Value
 
-		case *MaxInclusive:
-			if currentMsg == nil {
-				return currentMsg, fmt.Errorf("maxInclusive but no current message")
+		case *Length:
+			mi, err := currentItem(currentMsg, "length")
+			if err != nil {
+				return currentMsg, err
 			}
-			if len(currentMsg.MessageItems) == 0 {
-				return currentMsg, fmt.Errorf("maxInclusive but no current message item")
-			} else {
-				currentMsg.MessageItems[len(currentMsg.MessageItems)-1].MaxInclusive = t.Value
+			if mi.Length, err = facetInt("length", t.Value); err != nil {
+				return currentMsg, err
+			}
+
+		case *MinLength:
+			mi, err := currentItem(currentMsg, "minLength")
+			if err != nil {
+				return currentMsg, err
+			}
+			if mi.MinLength, err = facetInt("minLength", t.Value); err != nil {
+				return currentMsg, err
+			}
+
+		case *MaxLength:
+			mi, err := currentItem(currentMsg, "maxLength")
+			if err != nil {
+				return currentMsg, err
+			}
+			if mi.MaxLength, err = facetInt("maxLength", t.Value); err != nil {
+				return currentMsg, err
+			}
+
+		case *TotalDigits:
+			mi, err := currentItem(currentMsg, "totalDigits")
+			if err != nil {
+				return currentMsg, err
+			}
+			if mi.TotalDigits, err = facetInt("totalDigits", t.Value); err != nil {
+				return currentMsg, err
+			}
+
+		case *FractionDigits:
+			mi, err := currentItem(currentMsg, "fractionDigits")
+			if err != nil {
+				return currentMsg, err
+			}
+			if mi.FractionDigits, err = facetInt("fractionDigits", t.Value); err != nil {
+				return currentMsg, err
 			}
 
+		case *WhiteSpace:
+			mi, err := currentItem(currentMsg, "whiteSpace")
+			if err != nil {
+				return currentMsg, err
+			}
+			mi.WhiteSpace = t.Value
+
 		case *Enumeration:
 			if currentMsg == nil {
 				return currentMsg, fmt.Errorf("enumeration but no current message")
 			}
 			if i := len(currentMsg.MessageItems) - 1; i >= 0 {
-				currentMsg.MessageItems[i].Values = append(currentMsg.MessageItems[i].Values, t.Value)
+				mi := currentMsg.MessageItems[i]
+				mi.Values = append(mi.Values, t.Value)
+				if description := t.Annotation.Description(""); description > "" {
+					if mi.ValueDescriptions == nil {
+						mi.ValueDescriptions = make(map[string]string)
+					}
+					mi.ValueDescriptions[t.Value] = description
+				}
 			}
 		}
 		return currentMsg, nil
 	}
-	_, _ = xsd.ApplyFunctionP(fDisplay)
+	if _, err = xsd.ApplyFunctionP(fDisplay); err != nil {
+		return nil, err
+	}
 	for _, m := range messageMap {
 		messages = append(messages, m)
 	}
@@ -211,18 +562,86 @@ func (xsd *XSD) Messages(fmtStd string) (messages []*Message, err error) {
 		}
 		return messages[i].sequence < messages[j].sequence
 	})
+	markRecursive(messages)
 	return messages, nil
 }
 
+// describe sets the description of the message from the annotation, if it has documentation.
+// Annotations are documentation for the component they are in so they are picked up by the component
+func (m *Message) describe(a *Annotation) {
+	if description := a.Description(""); description > "" {
+		m.Description, m.Descriptions = description, a.Descriptions()
+	}
+}
+
+// describe sets the description of the message item from the annotation, if it has documentation
+func (mi *MessageItem) describe(a *Annotation) {
+	if description := a.Description(""); description > "" {
+		mi.Description, mi.Descriptions = description, a.Descriptions()
+	}
+}
+
+// simpleTypeItem is the message item a simple type describes, the item is created for a named simple type
+func simpleTypeItem(currentMsg *Message) *MessageItem {
+	if len(currentMsg.MessageItems) == 0 {
+		currentMsg.MessageItems = append(currentMsg.MessageItems, &MessageItem{Name: currentMsg.Name})
+	}
+	return currentMsg.MessageItems[len(currentMsg.MessageItems)-1]
+}
+
+// currentItem is the last message item of the current message, which is the item a facet applies to
+func currentItem(currentMsg *Message, facet string) (*MessageItem, error) {
+	if currentMsg == nil {
+		return nil, fmt.Errorf("%s but no current message", facet)
+	}
+	if len(currentMsg.MessageItems) == 0 {
+		return nil, fmt.Errorf("%s but no current message item", facet)
+	}
+	return currentMsg.MessageItems[len(currentMsg.MessageItems)-1], nil
+}
+
+// wildcardType is the type of the item holding the content of an xs:any or the attributes of an xs:anyAttribute
+func wildcardType(fmtStd string, attribute bool) string {
+	switch fmtStd {
+	case "protobuf":
+		if attribute {
+			return "map<string, string>"
+		}
+		return "google.protobuf.Any"
+	case "json":
+		return "object" // Allowing additionalProperties
+	}
+	if attribute {
+		return "anyAttribute"
+	}
+	return "any"
+}
+
+// facetInt converts the value of a length or digits facet
+func facetInt(facet, value string) (int, error) {
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("%s %q is not a non negative integer", facet, value)
+	}
+	return i, nil
+}
+
 // setTypeOrMessages tries to convert internal xsd Types to other message types
+// t is the type as written in the schema, qn is the resolved type and local is true if the type is in the schema.
 // This relies upon xsdTransMap being set
-func (mi *MessageItem) setTypeOrMessage(t string, messageMap map[string]*Message) {
+func (mi *MessageItem) setTypeOrMessage(t string, qn QName, local bool, messageMap map[string]*Message) {
 	if t == "" {
 		mi.Type = mi.Name
 	} else {
 		var inMap bool
 		var typeFmt tf
-		if typeFmt, inMap = xsdTransMap[t]; inMap {
+		if qn.Namespace == XMLSchemaNamespace {
+			var list bool
+			if typeFmt, list, inMap = builtinTypeFormat(qn.Local); list {
+				mi.Repeated = true
+			}
+		}
+		if inMap {
 			if typeFmt.t > "" {
 				mi.Type = typeFmt.t
 			}
@@ -247,6 +666,8 @@ func (mi *MessageItem) setTypeOrMessage(t string, messageMap map[string]*Message
 					mi.Values = append(mi.Values, v)
 				}
 			}
+		} else if local {
+			mi.Type = qn.Local
 		} else {
 			// Is this a Package:Message field
 			if pkgMsg := strings.Split(t, ":"); len(pkgMsg) > 1 {
@@ -270,43 +691,74 @@ func (mi *MessageItem) setTypeOrMessage(t string, messageMap map[string]*Message
 	}
 }
 
+// builtinTypeFormat finds the translation of a built in type. A type that isn't in xsdTransMap takes the translation
+// of the nearest type it's derived from with its own implicit range, e.g. unsignedShort is an int from 0 to 65535.
+// A built in list type such as IDREFS translates to its item type and list is true
+func builtinTypeFormat(name string) (typeFmt tf, list bool, inMap bool) {
+	if typeFmt, inMap = xsdTransMap[name]; inMap {
+		return
+	}
+	bt, found := LookupBuiltinType(name)
+	if !found {
+		return
+	}
+	if bt.IsList() {
+		typeFmt, _, inMap = builtinTypeFormat(bt.ItemType)
+		return typeFmt, true, inMap
+	}
+	for base := bt.BaseType(); base != nil && !inMap; base = base.BaseType() {
+		typeFmt, inMap = xsdTransMap[base.Name]
+	}
+	if inMap {
+		if bt.MinInclusive > "" {
+			typeFmt.minInclusive = bt.MinInclusive
+		}
+		if bt.MaxInclusive > "" {
+			typeFmt.maxInclusive = bt.MaxInclusive
+		}
+	}
+	return
+}
+
 func buildXsdTransMap(formatStandard string) {
 	switch formatStandard {
 	case "protobuf":
 		xsdTransMap = map[string]tf{
-			"xs:string":           {t: "string"},
-			"xs:normalizedString": {t: "string"},
-			"xs:token":            {t: "string"},
-			"xs:long":             {t: "int64"},
-			"xs:int":              {t: "int64"},
-			"xs:integer":          {t: "int64"},
-			"xs:positiveInteger":  {t: "int64", minInclusive: "0"},
-			"xs:float":            {t: "float"},
-			"xs:decimal":          {t: "float"},
-			"xs:double":           {t: "double"},
-			"xs:boolean":          {t: "bool"},
-			"xs:date":             {t: "google.protobuf.Timestamp"},
-			"xs:datetime":         {t: "google.protobuf.Timestamp"},
-			"xs:time":             {t: "google.protobuf.Timestamp"},
-			"duration":            {t: "google.protobuf.Duration"},
+			"string":           {t: "string"},
+			"normalizedString": {t: "string"},
+			"token":            {t: "string"},
+			"long":             {t: "int64"},
+			"int":              {t: "int64"},
+			"integer":          {t: "int64"},
+			"positiveInteger":  {t: "int64", minInclusive: "1"},
+			"float":            {t: "float"},
+			"decimal":          {t: "float"},
+			"double":           {t: "double"},
+			"boolean":          {t: "bool"},
+			"date":             {t: "google.protobuf.Timestamp"},
+			"dateTime":         {t: "google.protobuf.Timestamp"},
+			"time":             {t: "google.protobuf.Timestamp"},
+			"duration":         {t: "google.protobuf.Duration"},
+			"anySimpleType":    {t: "string"}, // Any other simple value, e.g. gYear or anyURI, is text
 		}
 	case "json":
 		xsdTransMap = map[string]tf{
-			"xs:string":           {t: "string"},
-			"xs:normalizedString": {t: "string"},
-			"xs:token":            {t: "string"},
-			"xs:long":             {t: "integer"},
-			"xs:int":              {t: "integer"},
-			"xs:integer":          {t: "integer"},
-			"xs:positiveInteger":  {t: "integer", minInclusive: "0"},
-			"xs:float":            {t: "number"},
-			"xs:decimal":          {t: "number"},
-			"xs:double":           {t: "number"},
-			"xs:boolean":          {t: "boolean"},
-			"xs:date":             {t: "date", f: "RFC 3339", values: []string{"2018-11-13"}},                     //New in draft 7 Date
-			"xs:datetime":         {t: "date-time", f: "RFC 3339", values: []string{"2018-11-13T20:20:39+00:00"}}, // Date and time together
-			"xs:time":             {t: "time", f: "RFC 3339", values: []string{"20:20:39+00:00"}},                 // New in draft 7 Time0
-			"duration":            {t: "duration", f: "ISO 8601 ABNF", values: []string{"P3D"}},                   //New in draft 2019-09 A duration as defined by the ISO 8601 ABNF for "duration". For example, P3D expresses a duration of 3 days
+			"string":           {t: "string"},
+			"normalizedString": {t: "string"},
+			"token":            {t: "string"},
+			"long":             {t: "integer"},
+			"int":              {t: "integer"},
+			"integer":          {t: "integer"},
+			"positiveInteger":  {t: "integer", minInclusive: "1"},
+			"float":            {t: "number"},
+			"decimal":          {t: "number"},
+			"double":           {t: "number"},
+			"boolean":          {t: "boolean"},
+			"date":             {t: "date", f: "RFC 3339", values: []string{"2018-11-13"}},                     //New in draft 7 Date
+			"dateTime":         {t: "date-time", f: "RFC 3339", values: []string{"2018-11-13T20:20:39+00:00"}}, // Date and time together
+			"time":             {t: "time", f: "RFC 3339", values: []string{"20:20:39+00:00"}},                 // New in draft 7 Time0
+			"duration":         {t: "duration", f: "ISO 8601 ABNF", values: []string{"P3D"}},                   //New in draft 2019-09 A duration as defined by the ISO 8601 ABNF for "duration". For example, P3D expresses a duration of 3 days
+			"anySimpleType":    {t: "string"},
 		}
 	default:
 		xsdTransMap = map[string]tf{} // No translation
@@ -0,0 +1,286 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// encoding/xml puts each kind of child into its own slice so the order of mixed particles is lost.
+// Sequence and Choice decode their children themselves and record the position of each one in the document.
+// The Elements, Sequences, Choices, Groups and Anys slices are the particles, the positions only give their order
+
+// UnmarshalXML decodes the attributes and particles of an xs:sequence
+func (s *Sequence) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	for _, a := range start.Attr {
+		switch a.Name.Local {
+		case "name":
+			s.Name = a.Value
+		case "minOccurs":
+			s.MinOccurs = a.Value
+		case "maxOccurs":
+			s.MaxOccurs = a.Value
+		}
+	}
+	var particles []XsdElement
+	if s.Annotation, particles, err = decodeParticles(d); err != nil {
+		return fmt.Errorf("could not decode sequence, got %v", err)
+	}
+	for _, p := range particles {
+		switch t := p.(type) {
+		case *Element:
+			s.Elements = append(s.Elements, t)
+		case *Sequence:
+			s.Sequences = append(s.Sequences, t)
+		case *Choice:
+			s.Choices = append(s.Choices, t)
+		case *Group:
+			s.Groups = append(s.Groups, t)
+		case *Any:
+			s.Anys = append(s.Anys, t)
+		}
+	}
+	return
+}
+
+// MarshalXML encodes the particles of an xs:sequence in order
+func (s *Sequence) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = appendAttrs(start.Attr, "name", s.Name, "minOccurs", s.MinOccurs, "maxOccurs", s.MaxOccurs)
+	return encodeParticles(e, start, s.Annotation, s.Particles())
+}
+
+// Particles are the children of the sequence in document order. Those without a position,
+// because they were added after unmarshalling, come last with elements first, then sequences, choices, groups and anys
+func (s *Sequence) Particles() []XsdElement {
+	return inOrder(s.Elements, s.Sequences, s.Choices, s.Groups, s.Anys)
+}
+
+// UnmarshalXML decodes the attributes and particles of an xs:choice
+func (ch *Choice) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err error) {
+	for _, a := range start.Attr {
+		switch a.Name.Local {
+		case "name":
+			ch.Name = a.Value
+		case "minOccurs":
+			ch.MinOccurs = a.Value
+		case "maxOccurs":
+			ch.MaxOccurs = a.Value
+		}
+	}
+	var particles []XsdElement
+	if ch.Annotation, particles, err = decodeParticles(d); err != nil {
+		return fmt.Errorf("could not decode choice, got %v", err)
+	}
+	for _, p := range particles {
+		switch t := p.(type) {
+		case *Element:
+			ch.Elements = append(ch.Elements, t)
+		case *Sequence:
+			ch.Sequences = append(ch.Sequences, t)
+		case *Choice:
+			ch.Choices = append(ch.Choices, t)
+		case *Group:
+			ch.Groups = append(ch.Groups, t)
+		case *Any:
+			ch.Anys = append(ch.Anys, t)
+		}
+	}
+	return
+}
+
+// MarshalXML encodes the particles of an xs:choice in order
+func (ch *Choice) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = appendAttrs(start.Attr, "name", ch.Name, "minOccurs", ch.MinOccurs, "maxOccurs", ch.MaxOccurs)
+	return encodeParticles(e, start, ch.Annotation, ch.Particles())
+}
+
+// Particles are the alternatives of the choice in document order, see Sequence.Particles
+func (ch *Choice) Particles() []XsdElement {
+	return inOrder(ch.Elements, ch.Sequences, ch.Choices, ch.Groups, ch.Anys)
+}
+
+// inOrder merges the kinds of particles by their position. Each kind is already in document order
+// so the next particle is always the first one left of one of the kinds
+func inOrder(elements []*Element, sequences []*Sequence, choices []*Choice, groups []*Group, anys []*Any) (particles []XsdElement) {
+	kinds := particleKinds(elements, sequences, choices, groups, anys)
+	next := make([]int, len(kinds))
+	for {
+		k := -1
+		for i, kind := range kinds {
+			if next[i] < len(kind) && (k < 0 || before(kind[next[i]], kinds[k][next[k]])) {
+				k = i
+			}
+		}
+		if k < 0 {
+			return
+		}
+		particles = append(particles, kinds[k][next[k]])
+		next[k]++
+	}
+}
+
+// before is true if particle p comes before other. A particle added after unmarshalling has no position
+// and comes after those that have
+func before(p, other XsdElement) bool {
+	position, otherPosition := particlePosition(p), particlePosition(other)
+	return position > 0 && (otherPosition == 0 || position < otherPosition)
+}
+
+func particlePosition(p XsdElement) int {
+	switch t := p.(type) {
+	case *Element:
+		return t.position
+	case *Sequence:
+		return t.position
+	case *Choice:
+		return t.position
+	case *Group:
+		return t.position
+	case *Any:
+		return t.position
+	}
+	return 0
+}
+
+func particleKinds(elements []*Element, sequences []*Sequence, choices []*Choice, groups []*Group, anys []*Any) (kinds [][]XsdElement) {
+	kinds = make([][]XsdElement, 5)
+	for _, e := range elements {
+		kinds[0] = append(kinds[0], e)
+	}
+	for _, s := range sequences {
+		kinds[1] = append(kinds[1], s)
+	}
+	for _, ch := range choices {
+		kinds[2] = append(kinds[2], ch)
+	}
+	for _, g := range groups {
+		kinds[3] = append(kinds[3], g)
+	}
+	for _, a := range anys {
+		kinds[4] = append(kinds[4], a)
+	}
+	return
+}
+
+// decodeParticles decodes the annotation and particles of a sequence or choice up to its end element, anything else is skipped
+func decodeParticles(d *xml.Decoder) (annotation *Annotation, particles []XsdElement, err error) {
+	for {
+		var token xml.Token
+		if token, err = d.Token(); err != nil {
+			return
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var p XsdElement
+			switch t.Name.Local {
+			case "annotation":
+				annotation = &Annotation{}
+				if err = d.DecodeElement(annotation, &t); err != nil {
+					return
+				}
+				continue
+			case "element":
+				p = &Element{position: len(particles) + 1}
+			case "sequence":
+				p = &Sequence{position: len(particles) + 1}
+			case "choice":
+				p = &Choice{position: len(particles) + 1}
+			case "group":
+				p = &Group{position: len(particles) + 1}
+			case "any":
+				p = &Any{position: len(particles) + 1}
+			default:
+				if err = d.Skip(); err != nil {
+					return
+				}
+				continue
+			}
+			if err = d.DecodeElement(p, &t); err != nil {
+				return
+			}
+			particles = append(particles, p)
+		case xml.EndElement:
+			return
+		}
+	}
+}
+
+// encodeParticles writes the start element, the annotation, the particles and the end element
+func encodeParticles(e *xml.Encoder, start xml.StartElement, annotation *Annotation, particles []XsdElement) (err error) {
+	if err = e.EncodeToken(start); err != nil {
+		return
+	}
+	if annotation != nil {
+		if err = e.EncodeElement(annotation, xml.StartElement{Name: xml.Name{Space: start.Name.Space, Local: "annotation"}}); err != nil {
+			return
+		}
+	}
+	for _, p := range particles {
+		var local string
+		switch p.(type) {
+		case *Element:
+			local = "element"
+		case *Sequence:
+			local = "sequence"
+		case *Choice:
+			local = "choice"
+		case *Group:
+			local = "group"
+		case *Any:
+			local = "any"
+		default:
+			return fmt.Errorf("%s is not a particle", p.ToString())
+		}
+		if err = e.EncodeElement(p, xml.StartElement{Name: xml.Name{Space: start.Name.Space, Local: local}}); err != nil {
+			return
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// appendAttrs appends name value pairs as attributes, empty values are left out
+func appendAttrs(attrs []xml.Attr, nameValues ...string) []xml.Attr {
+	for i := 0; i+1 < len(nameValues); i += 2 {
+		if nameValues[i+1] > "" {
+			attrs = append(attrs, xml.Attr{Name: xml.Name{Local: nameValues[i]}, Value: nameValues[i+1]})
+		}
+	}
+	return attrs
+}
+
+// applyFunctionParticle applies a function to a particle of a content model, sequence or choice
+func applyFunctionParticle(f func(XsdElement) error, p XsdElement) error {
+	switch t := p.(type) {
+	case *Element:
+		return t.applyFunction(f)
+	case *Sequence:
+		return t.applyFunction(f)
+	case *Choice:
+		return t.applyFunction(f)
+	case *Group:
+		return t.applyFunction(f)
+	case *All:
+		return t.applyFunction(f)
+	case *Any:
+		return t.applyFunction(f)
+	}
+	return f(p)
+}
+
+// applyFunctionPParticle applies a function to a particle of a content model, sequence or choice
+func applyFunctionPParticle(f func(XsdElement, interface{}) (interface{}, error), p XsdElement, parent interface{}) (interface{}, error) {
+	switch t := p.(type) {
+	case *Element:
+		return t.applyFunctionP(f, parent)
+	case *Sequence:
+		return t.applyFunctionP(f, parent)
+	case *Choice:
+		return t.applyFunctionP(f, parent)
+	case *Group:
+		return t.applyFunctionP(f, parent)
+	case *All:
+		return t.applyFunctionP(f, parent)
+	case *Any:
+		return t.applyFunctionP(f, parent)
+	}
+	return f(p, parent)
+}
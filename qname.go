@@ -0,0 +1,217 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+const (
+	XMLSchemaNamespace = "http://www.w3.org/2001/XMLSchema"
+	XMLNamespace       = "http://www.w3.org/XML/1998/namespace"
+)
+
+// QName is a reference such as "xs:string" or "tns:Address" resolved against the namespace declarations of its schema
+type QName struct {
+	Namespace string
+	Local     string
+}
+
+// String returns the name in {namespace}local form
+func (qn QName) String() string {
+	if qn.Namespace == "" {
+		return qn.Local
+	}
+	return "{" + qn.Namespace + "}" + qn.Local
+}
+
+// SchemaAttr is an attribute of the schema element that isn't otherwise modelled, mostly xmlns declarations.
+// encoding/xml can't marshal namespace declarations held in an xml.Attr so this type writes them back as they were
+type SchemaAttr xml.Attr
+
+func (a *SchemaAttr) UnmarshalXMLAttr(attr xml.Attr) error {
+	*a = SchemaAttr(attr)
+	return nil
+}
+
+func (a SchemaAttr) MarshalXMLAttr(_ xml.Name) (xml.Attr, error) {
+	if a.Name.Space == "xmlns" {
+		return xml.Attr{Name: xml.Name{Local: "xmlns:" + a.Name.Local}, Value: a.Value}, nil
+	}
+	return xml.Attr(a), nil
+}
+
+// Namespaces returns the namespace declarations of the schema by prefix, the default namespace has the prefix ""
+func (xsd *XSD) Namespaces() map[string]string {
+	namespaces := make(map[string]string)
+	for _, a := range xsd.Attrs {
+		switch {
+		case a.Name.Space == "xmlns":
+			namespaces[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			namespaces[""] = a.Value
+		}
+	}
+	return namespaces
+}
+
+// namespaceOf finds the namespace bound to the prefix
+func (xsd *XSD) namespaceOf(prefix string) (namespace string, found bool) {
+	if prefix == "xml" {
+		return XMLNamespace, true
+	}
+	namespace, found = xsd.Namespaces()[prefix]
+	return
+}
+
+// ResolveQName resolves a prefixed name using the namespace declarations of the schema.
+// Unprefixed names are in the default namespace, or no namespace if there isn't one
+// (the including schema's target namespace for a chameleon include)
+func (xsd *XSD) ResolveQName(name string) (qn QName) {
+	prefix, local, hasPrefix := strings.Cut(name, ":")
+	if !hasPrefix {
+		prefix, local = "", name
+	}
+	qn.Local = local
+	var found bool
+	if qn.Namespace, found = xsd.namespaceOf(prefix); !found && !hasPrefix && xsd.chameleon {
+		qn.Namespace = xsd.TargetNamespace
+	}
+	return
+}
+
+// isLocalName is true if the name is in a namespace of this schema or the schemas combined into it
+func (xsd *XSD) isLocalName(qn QName) bool {
+	if qn.Namespace == xsd.TargetNamespace {
+		return true
+	}
+	for _, s := range xsd.Schemas {
+		if qn.Namespace == s.TargetNamespace {
+			return true
+		}
+	}
+	return false
+}
+
+// qualified tells if a local element or attribute name is in the target namespace
+func qualified(form, formDefault string) bool {
+	if form > "" {
+		return form == "qualified"
+	}
+	return formDefault == "qualified"
+}
+
+// resolveQNames sets the namespace of the declarations and resolves the type, ref and base names of the schema
+func (xsd *XSD) resolveQNames() {
+	fResolve := func(xe XsdElement, parent interface{}) (interface{}, error) {
+		global := parent == xsd
+		switch t := xe.(type) {
+		case *XSD:
+			return t, nil
+		case *ComplexType:
+			t.Namespace = xsd.TargetNamespace
+		case *SimpleType:
+			t.Namespace = xsd.TargetNamespace
+		case *Element:
+			t.Namespace = ""
+			if t.Ref > "" {
+				t.RefQName = xsd.ResolveQName(t.Ref)
+				t.Namespace = t.RefQName.Namespace
+			} else if global || qualified(t.Form, xsd.ElementFormDefault) {
+				t.Namespace = xsd.TargetNamespace
+			}
+			if t.Type > "" {
+				t.TypeQName = xsd.ResolveQName(t.Type)
+			}
+			t.SubstitutionGroupQNames = nil
+			for _, head := range strings.Fields(t.SubstitutionGroup) {
+				t.SubstitutionGroupQNames = append(t.SubstitutionGroupQNames, xsd.ResolveQName(head))
+			}
+		case *Attribute:
+			t.Namespace = ""
+			if t.Ref > "" {
+				t.RefQName = xsd.ResolveQName(t.Ref)
+				t.Namespace = t.RefQName.Namespace
+			} else if global || qualified(t.Form, xsd.AttributeFormDefault) {
+				t.Namespace = xsd.TargetNamespace
+			}
+			if t.Type > "" {
+				t.TypeQName = xsd.ResolveQName(t.Type)
+			}
+		case *Restriction:
+			if t.Base > "" {
+				t.BaseQName = xsd.ResolveQName(t.Base)
+			}
+		case *Extension:
+			if t.Base > "" {
+				t.BaseQName = xsd.ResolveQName(t.Base)
+			}
+		case *List:
+			if t.ItemType > "" {
+				t.ItemTypeQName = xsd.ResolveQName(t.ItemType)
+			}
+		case *Union:
+			t.MemberTypeQNames = nil
+			for _, m := range strings.Fields(t.MemberTypes) {
+				t.MemberTypeQNames = append(t.MemberTypeQNames, xsd.ResolveQName(m))
+			}
+		case *Group:
+			t.Namespace = xsd.TargetNamespace
+			if t.Ref > "" {
+				t.RefQName = xsd.ResolveQName(t.Ref)
+			}
+		case *AttributeGroup:
+			t.Namespace = xsd.TargetNamespace
+			if t.Ref > "" {
+				t.RefQName = xsd.ResolveQName(t.Ref)
+			}
+		case *Alternative:
+			if t.Type > "" {
+				t.TypeQName = xsd.ResolveQName(t.Type)
+			}
+		case *Unique:
+			t.Namespace = xsd.TargetNamespace
+		case *Key:
+			t.Namespace = xsd.TargetNamespace
+		case *KeyRef:
+			t.Namespace = xsd.TargetNamespace
+			t.ReferQName = xsd.ResolveQName(t.Refer)
+		}
+		return xe, nil
+	}
+	_, _ = xsd.ApplyFunctionP(fResolve)
+	// Redefined and overriding components aren't walked with the schema
+	for _, r := range xsd.Redefines {
+		for _, st := range r.SimpleTypes {
+			_, _ = st.applyFunctionP(fResolve, xsd)
+		}
+		for _, ct := range r.ComplexTypes {
+			_, _ = ct.applyFunctionP(fResolve, xsd)
+		}
+		for _, g := range r.Groups {
+			_, _ = g.applyFunctionP(fResolve, xsd)
+		}
+		for _, ag := range r.AttributeGroups {
+			_, _ = ag.applyFunctionP(fResolve, xsd)
+		}
+	}
+	for _, o := range xsd.Overrides {
+		for _, st := range o.SimpleTypes {
+			_, _ = st.applyFunctionP(fResolve, xsd)
+		}
+		for _, ct := range o.ComplexTypes {
+			_, _ = ct.applyFunctionP(fResolve, xsd)
+		}
+		for _, e := range o.Elements {
+			_, _ = e.applyFunctionP(fResolve, xsd)
+		}
+		for _, a := range o.Attributes {
+			_, _ = a.applyFunctionP(fResolve, xsd)
+		}
+		for _, g := range o.Groups {
+			_, _ = g.applyFunctionP(fResolve, xsd)
+		}
+		for _, ag := range o.AttributeGroups {
+			_, _ = ag.applyFunctionP(fResolve, xsd)
+		}
+	}
+}
@@ -0,0 +1,609 @@
+package xsd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// XSD regular expressions match the whole value, have character class subtraction, e.g. [a-z-[aeiou]], the XML name
+// escapes \i and \c and the Unicode block escapes like \p{IsBasicLatin}. They are translated to Go regular expressions,
+// character classes become the list of ranges they match so subtraction and negated escapes work inside a class
+
+// RegexpError is an XSD regular expression that can't be translated
+type RegexpError struct {
+	Pattern string
+	Offset  int // The byte offset of the problem in the pattern
+	Message string
+}
+
+func (e *RegexpError) Error() string {
+	return fmt.Sprintf("pattern %q at offset %d: %s", e.Pattern, e.Offset, e.Message)
+}
+
+// TranslateRegexp rewrites an XSD regular expression as a Go regular expression anchored to match the whole value
+func TranslateRegexp(pattern string) (string, error) {
+	p := &regexpParser{pattern: pattern}
+	translated, err := p.regExp()
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.pattern) { // Only an unmatched ) stops the top level
+		return "", p.errorf("there is no ( for the )")
+	}
+	return "^(?:" + translated + ")$", nil
+}
+
+// CompileRegexp compiles an XSD regular expression, it matches a value as a pattern facet does
+func CompileRegexp(pattern string) (*regexp.Regexp, error) {
+	translated, err := TranslateRegexp(pattern)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(translated)
+	if err != nil { // e.g. a repeat count over 1000
+		return nil, &RegexpError{Pattern: pattern, Message: strings.TrimPrefix(err.Error(), "error parsing regexp: ")}
+	}
+	return re, nil
+}
+
+// MustCompileRegexp is like CompileRegexp but panics if the pattern can't be compiled
+func MustCompileRegexp(pattern string) *regexp.Regexp {
+	re, err := CompileRegexp(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return re
+}
+
+type regexpParser struct {
+	pattern string
+	pos     int
+}
+
+func (p *regexpParser) errorf(format string, a ...interface{}) error {
+	return &RegexpError{Pattern: p.pattern, Offset: p.pos, Message: fmt.Sprintf(format, a...)}
+}
+
+func (p *regexpParser) more() bool {
+	return p.pos < len(p.pattern)
+}
+
+func (p *regexpParser) peek() byte {
+	if !p.more() {
+		return 0
+	}
+	return p.pattern[p.pos]
+}
+
+// next reads a character
+func (p *regexpParser) next() rune {
+	for i, r := range p.pattern[p.pos:] {
+		p.pos += i + len(string(r))
+		return r
+	}
+	return 0
+}
+
+// regExp is branches separated by |, up to the end of the pattern or a )
+func (p *regexpParser) regExp() (string, error) {
+	var branches []string
+	for {
+		branch, err := p.branch()
+		if err != nil {
+			return "", err
+		}
+		branches = append(branches, branch)
+		if p.peek() != '|' {
+			return strings.Join(branches, "|"), nil
+		}
+		p.pos++
+	}
+}
+
+func (p *regexpParser) branch() (string, error) {
+	var sb strings.Builder
+	for p.more() && p.peek() != '|' && p.peek() != ')' {
+		atom, err := p.atom()
+		if err != nil {
+			return "", err
+		}
+		quantifier, err := p.quantifier()
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(atom + quantifier)
+	}
+	return sb.String(), nil
+}
+
+func (p *regexpParser) atom() (string, error) {
+	switch c := p.peek(); c {
+	case '(':
+		p.pos++
+		re, err := p.regExp()
+		if err != nil {
+			return "", err
+		}
+		if p.peek() != ')' {
+			return "", p.errorf("there is no ) for the (")
+		}
+		p.pos++
+		return "(?:" + re + ")", nil
+	case '[':
+		set, err := p.charClassExpr()
+		if err != nil {
+			return "", err
+		}
+		return set.String(), nil
+	case '.':
+		p.pos++
+		return `[^\n\r]`, nil
+	case '\\':
+		start := p.pos
+		set, single, err := p.escape()
+		if err != nil {
+			return "", err
+		}
+		if set == nil {
+			return regexp.QuoteMeta(string(single)), nil
+		}
+		if escape := p.pattern[start:p.pos]; goEscape(escape) { // Kept as it is, a list of ranges would be long
+			return strings.Replace(escape, `\d`, `\p{Nd}`, 1), nil
+		}
+		return set.String(), nil
+	case '?', '*', '+', '{':
+		return "", p.errorf("%c has to follow something to repeat", c)
+	case ']', '}':
+		return "", p.errorf("%c has to be escaped", c)
+	}
+	return regexp.QuoteMeta(string(p.next())), nil
+}
+
+// goEscape is true for the escapes Go regular expressions have with the same meaning
+func goEscape(escape string) bool {
+	switch escape {
+	case `\d`:
+		return true
+	}
+	if len(escape) < 4 || (escape[1] != 'p' && escape[1] != 'P') {
+		return false
+	}
+	category := escape[3 : len(escape)-1]
+	_, found := unicode.Categories[category]
+	return found
+}
+
+// quantifier is ?, *, +, {n}, {n,} or {n,m} after an atom
+func (p *regexpParser) quantifier() (string, error) {
+	switch p.peek() {
+	case '?', '*', '+':
+		q := p.pattern[p.pos : p.pos+1]
+		p.pos++
+		if c := p.peek(); c == '?' || c == '*' || c == '+' || c == '{' {
+			return "", p.errorf("%c can't repeat a repeat, lazy and possessive quantifiers aren't XSD", c)
+		}
+		return q, nil
+	case '{':
+	default:
+		return "", nil
+	}
+	start := p.pos
+	end := strings.IndexByte(p.pattern[p.pos:], '}')
+	if end < 0 {
+		return "", p.errorf("there is no } for the {")
+	}
+	quantity := p.pattern[p.pos+1 : p.pos+end]
+	low, high, hasComma := strings.Cut(quantity, ",")
+	minimum, err := strconv.Atoi(low)
+	if err != nil || strings.HasPrefix(low, "+") {
+		return "", p.errorf("{%s} isn't a quantity, it's {n}, {n,} or {n,m}", quantity)
+	}
+	if hasComma && high > "" {
+		maximum, err := strconv.Atoi(high)
+		if err != nil || strings.HasPrefix(high, "+") {
+			return "", p.errorf("{%s} isn't a quantity, it's {n}, {n,} or {n,m}", quantity)
+		}
+		if maximum < minimum {
+			return "", p.errorf("{%s} has a maximum less than its minimum", quantity)
+		}
+	}
+	p.pos += end + 1
+	if c := p.peek(); c == '?' || c == '*' || c == '+' || c == '{' {
+		return "", p.errorf("%c can't repeat a repeat, lazy and possessive quantifiers aren't XSD", c)
+	}
+	return p.pattern[start:p.pos], nil
+}
+
+// charClassExpr is [...], [^...] or either with a subtraction [...-[...]]
+func (p *regexpParser) charClassExpr() (set runeSet, err error) {
+	start := p.pos
+	p.pos++ // [
+	negated := p.peek() == '^'
+	if negated {
+		p.pos++
+	}
+	first := true
+	for {
+		if !p.more() {
+			p.pos = start
+			return nil, p.errorf("there is no ] for the [")
+		}
+		switch c := p.peek(); {
+		case c == ']':
+			if first {
+				return nil, p.errorf("a character class can't be empty")
+			}
+			p.pos++
+			if negated {
+				set = set.negate()
+			}
+			return
+		case c == '-' && strings.HasPrefix(p.pattern[p.pos:], "-["):
+			if first {
+				return nil, p.errorf("a character class can't be empty")
+			}
+			p.pos++
+			subtracted, err := p.charClassExpr()
+			if err != nil {
+				return nil, err
+			}
+			if p.peek() != ']' {
+				return nil, p.errorf("the subtraction has to be the end of the character class")
+			}
+			p.pos++
+			if negated {
+				set = set.negate()
+			}
+			return set.subtract(subtracted), nil
+		case c == '[':
+			return nil, p.errorf("[ has to be escaped in a character class")
+		}
+		first = false
+		item, low, err := p.classChar()
+		if err != nil {
+			return nil, err
+		}
+		if item != nil {
+			set = set.union(item)
+			continue
+		}
+		// A range, unless the - is the last character of the class or starts a subtraction
+		if p.peek() != '-' || strings.HasPrefix(p.pattern[p.pos:], "-]") || strings.HasPrefix(p.pattern[p.pos:], "-[") {
+			set = set.union(runeSet{{low, low}})
+			continue
+		}
+		p.pos++
+		if p.peek() == '\\' && p.pos+1 < len(p.pattern) && strings.ContainsRune("sSiIcCdDwWpP", rune(p.pattern[p.pos+1])) {
+			return nil, p.errorf("a range can't end with a multi character escape")
+		}
+		item, high, err := p.classChar()
+		switch {
+		case err != nil:
+			return nil, err
+		case item != nil:
+			return nil, p.errorf("a range can't end with a multi character escape")
+		case high < low:
+			return nil, p.errorf("the range %c-%c is out of order", low, high)
+		}
+		set = set.union(runeSet{{low, high}})
+	}
+}
+
+// classChar is a character or escape in a character class, the set is nil for a single character
+func (p *regexpParser) classChar() (set runeSet, single rune, err error) {
+	if p.peek() == '\\' {
+		return p.escape()
+	}
+	return nil, p.next(), nil
+}
+
+// escape is a single character escape, e.g. \n, or a multi character escape, e.g. \d or \p{Lu}, which is a set
+func (p *regexpParser) escape() (set runeSet, single rune, err error) {
+	p.pos++ // \
+	if !p.more() {
+		return nil, 0, p.errorf(`\ has to be followed by the character it escapes`)
+	}
+	c := p.next()
+	switch c {
+	case 'n':
+		return nil, '\n', nil
+	case 'r':
+		return nil, '\r', nil
+	case 't':
+		return nil, '\t', nil
+	case '\\', '|', '.', '?', '*', '+', '(', ')', '{', '}', '-', '[', ']', '^':
+		return nil, c, nil
+	case 's', 'S', 'i', 'I', 'c', 'C', 'd', 'D', 'w', 'W':
+		set = multiCharEscapes[unicode.ToLower(c)]
+	case 'p', 'P':
+		if set, err = p.property(); err != nil {
+			return nil, 0, err
+		}
+	default:
+		p.pos -= len(string(c)) + 1
+		return nil, 0, p.errorf(`\%c isn't an XSD escape`, c)
+	}
+	if unicode.IsUpper(c) {
+		set = set.negate()
+	}
+	return set, 0, nil
+}
+
+// property is the {name} of \p{name}, a general category or a block
+func (p *regexpParser) property() (runeSet, error) {
+	if p.peek() != '{' {
+		return nil, p.errorf(`\p and \P have to be followed by {name}`)
+	}
+	end := strings.IndexByte(p.pattern[p.pos:], '}')
+	if end < 0 {
+		return nil, p.errorf("there is no } for the {")
+	}
+	name := p.pattern[p.pos+1 : p.pos+end]
+	if block, isBlock := strings.CutPrefix(name, "Is"); isBlock {
+		set, found := unicodeBlocks[block]
+		if !found {
+			return nil, p.errorf("%s isn't a Unicode block", block)
+		}
+		p.pos += end + 1
+		return set, nil
+	}
+	set := categorySet(name)
+	if set == nil {
+		return nil, p.errorf("%s isn't a Unicode general category", name)
+	}
+	p.pos += end + 1
+	return set, nil
+}
+
+// categorySet is the set of a general category, Go doesn't have Cn, the characters without a category
+func categorySet(name string) runeSet {
+	if name == "Cn" {
+		var assigned runeSet
+		for _, category := range []string{"L", "M", "N", "P", "S", "Z", "Cc", "Cf", "Co", "Cs"} {
+			assigned = assigned.union(tableSet(unicode.Categories[category]))
+		}
+		return assigned.negate()
+	}
+	if table, found := unicode.Categories[name]; found {
+		return tableSet(table)
+	}
+	return nil
+}
+
+// runeSet is a sorted list of ranges of characters that don't overlap or touch
+type runeSet []runeRange
+
+type runeRange struct {
+	low, high rune
+}
+
+func tableSet(table *unicode.RangeTable) (set runeSet) {
+	add := func(low, high, stride rune) {
+		if stride == 1 {
+			set = append(set, runeRange{low, high})
+			return
+		}
+		for c := low; c <= high; c += stride {
+			set = append(set, runeRange{c, c})
+		}
+	}
+	for _, r := range table.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range table.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	return set.union(nil)
+}
+
+// union merges the ranges of both sets
+func (s runeSet) union(t runeSet) (merged runeSet) {
+	all := append(append(runeSet{}, s...), t...)
+	sort.Slice(all, func(i, j int) bool { return all[i].low < all[j].low })
+	for _, r := range all {
+		if last := len(merged) - 1; last >= 0 && r.low <= merged[last].high+1 {
+			if r.high > merged[last].high {
+				merged[last].high = r.high
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return
+}
+
+// negate is every character not in the set
+func (s runeSet) negate() (negated runeSet) {
+	next := rune(0)
+	for _, r := range s {
+		if r.low > next {
+			negated = append(negated, runeRange{next, r.low - 1})
+		}
+		next = r.high + 1
+	}
+	if next <= unicode.MaxRune {
+		negated = append(negated, runeRange{next, unicode.MaxRune})
+	}
+	return
+}
+
+func (s runeSet) subtract(t runeSet) runeSet {
+	return s.negate().union(t).negate()
+}
+
+// String is the set as a Go character class
+func (s runeSet) String() string {
+	if len(s) == 0 {
+		return `[^\x00-\x{10FFFF}]` // Nothing matches
+	}
+	var sb strings.Builder
+	sb.WriteByte('[')
+	for _, r := range s {
+		sb.WriteString(classRune(r.low))
+		if r.high > r.low {
+			if r.high > r.low+1 {
+				sb.WriteByte('-')
+			}
+			sb.WriteString(classRune(r.high))
+		}
+	}
+	sb.WriteByte(']')
+	return sb.String()
+}
+
+func classRune(r rune) string {
+	if r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+		return string(r)
+	}
+	return fmt.Sprintf(`\x{%X}`, r)
+}
+
+// multiCharEscapes are the sets of \s, \i, \c, \d and \w, the upper case escapes are the rest of the characters
+var multiCharEscapes = map[rune]runeSet{
+	's': runeSet{{'\t', '\n'}, {'\r', '\r'}, {' ', ' '}},
+	'i': nameStartChars,
+	'c': nameStartChars.union(runeSet{{'-', '.'}, {'0', '9'}, {0xB7, 0xB7}, {0x300, 0x36F}, {0x203F, 0x2040}}),
+	'd': tableSet(unicode.Nd),
+	'w': tableSet(unicode.P).union(tableSet(unicode.Z)).union(tableSet(unicode.C)).negate(),
+}
+
+// nameStartChars are the characters an XML name can start with
+var nameStartChars = runeSet{{':', ':'}, {'A', 'Z'}, {'_', '_'}, {'a', 'z'}, {0xC0, 0xD6}, {0xD8, 0xF6}, {0xF8, 0x2FF},
+	{0x370, 0x37D}, {0x37F, 0x1FFF}, {0x200C, 0x200D}, {0x2070, 0x218F}, {0x2C00, 0x2FEF}, {0x3001, 0xD7FF},
+	{0xF900, 0xFDCF}, {0xFDF0, 0xFFFD}, {0x10000, 0xEFFFF}}
+
+// unicodeBlocks are the blocks of \p{IsName}, with the names XSD uses
+var unicodeBlocks = map[string]runeSet{
+	"BasicLatin":                           {{0x0000, 0x007F}},
+	"Latin-1Supplement":                    {{0x0080, 0x00FF}},
+	"LatinExtended-A":                      {{0x0100, 0x017F}},
+	"LatinExtended-B":                      {{0x0180, 0x024F}},
+	"IPAExtensions":                        {{0x0250, 0x02AF}},
+	"SpacingModifierLetters":               {{0x02B0, 0x02FF}},
+	"CombiningDiacriticalMarks":            {{0x0300, 0x036F}},
+	"Greek":                                {{0x0370, 0x03FF}},
+	"GreekandCoptic":                       {{0x0370, 0x03FF}},
+	"Cyrillic":                             {{0x0400, 0x04FF}},
+	"CyrillicSupplement":                   {{0x0500, 0x052F}},
+	"Armenian":                             {{0x0530, 0x058F}},
+	"Hebrew":                               {{0x0590, 0x05FF}},
+	"Arabic":                               {{0x0600, 0x06FF}},
+	"Syriac":                               {{0x0700, 0x074F}},
+	"Thaana":                               {{0x0780, 0x07BF}},
+	"Devanagari":                           {{0x0900, 0x097F}},
+	"Bengali":                              {{0x0980, 0x09FF}},
+	"Gurmukhi":                             {{0x0A00, 0x0A7F}},
+	"Gujarati":                             {{0x0A80, 0x0AFF}},
+	"Oriya":                                {{0x0B00, 0x0B7F}},
+	"Tamil":                                {{0x0B80, 0x0BFF}},
+	"Telugu":                               {{0x0C00, 0x0C7F}},
+	"Kannada":                              {{0x0C80, 0x0CFF}},
+	"Malayalam":                            {{0x0D00, 0x0D7F}},
+	"Sinhala":                              {{0x0D80, 0x0DFF}},
+	"Thai":                                 {{0x0E00, 0x0E7F}},
+	"Lao":                                  {{0x0E80, 0x0EFF}},
+	"Tibetan":                              {{0x0F00, 0x0FFF}},
+	"Myanmar":                              {{0x1000, 0x109F}},
+	"Georgian":                             {{0x10A0, 0x10FF}},
+	"HangulJamo":                           {{0x1100, 0x11FF}},
+	"Ethiopic":                             {{0x1200, 0x137F}},
+	"Cherokee":                             {{0x13A0, 0x13FF}},
+	"UnifiedCanadianAboriginalSyllabics":   {{0x1400, 0x167F}},
+	"Ogham":                                {{0x1680, 0x169F}},
+	"Runic":                                {{0x16A0, 0x16FF}},
+	"Tagalog":                              {{0x1700, 0x171F}},
+	"Hanunoo":                              {{0x1720, 0x173F}},
+	"Buhid":                                {{0x1740, 0x175F}},
+	"Tagbanwa":                             {{0x1760, 0x177F}},
+	"Khmer":                                {{0x1780, 0x17FF}},
+	"Mongolian":                            {{0x1800, 0x18AF}},
+	"Limbu":                                {{0x1900, 0x194F}},
+	"TaiLe":                                {{0x1950, 0x197F}},
+	"KhmerSymbols":                         {{0x19E0, 0x19FF}},
+	"PhoneticExtensions":                   {{0x1D00, 0x1D7F}},
+	"LatinExtendedAdditional":              {{0x1E00, 0x1EFF}},
+	"GreekExtended":                        {{0x1F00, 0x1FFF}},
+	"GeneralPunctuation":                   {{0x2000, 0x206F}},
+	"SuperscriptsandSubscripts":            {{0x2070, 0x209F}},
+	"CurrencySymbols":                      {{0x20A0, 0x20CF}},
+	"CombiningMarksforSymbols":             {{0x20D0, 0x20FF}},
+	"CombiningDiacriticalMarksforSymbols":  {{0x20D0, 0x20FF}},
+	"LetterlikeSymbols":                    {{0x2100, 0x214F}},
+	"NumberForms":                          {{0x2150, 0x218F}},
+	"Arrows":                               {{0x2190, 0x21FF}},
+	"MathematicalOperators":                {{0x2200, 0x22FF}},
+	"MiscellaneousTechnical":               {{0x2300, 0x23FF}},
+	"ControlPictures":                      {{0x2400, 0x243F}},
+	"OpticalCharacterRecognition":          {{0x2440, 0x245F}},
+	"EnclosedAlphanumerics":                {{0x2460, 0x24FF}},
+	"BoxDrawing":                           {{0x2500, 0x257F}},
+	"BlockElements":                        {{0x2580, 0x259F}},
+	"GeometricShapes":                      {{0x25A0, 0x25FF}},
+	"MiscellaneousSymbols":                 {{0x2600, 0x26FF}},
+	"Dingbats":                             {{0x2700, 0x27BF}},
+	"MiscellaneousMathematicalSymbols-A":   {{0x27C0, 0x27EF}},
+	"SupplementalArrows-A":                 {{0x27F0, 0x27FF}},
+	"BraillePatterns":                      {{0x2800, 0x28FF}},
+	"SupplementalArrows-B":                 {{0x2900, 0x297F}},
+	"MiscellaneousMathematicalSymbols-B":   {{0x2980, 0x29FF}},
+	"SupplementalMathematicalOperators":    {{0x2A00, 0x2AFF}},
+	"MiscellaneousSymbolsandArrows":        {{0x2B00, 0x2BFF}},
+	"CJKRadicalsSupplement":                {{0x2E80, 0x2EFF}},
+	"KangxiRadicals":                       {{0x2F00, 0x2FDF}},
+	"IdeographicDescriptionCharacters":     {{0x2FF0, 0x2FFF}},
+	"CJKSymbolsandPunctuation":             {{0x3000, 0x303F}},
+	"Hiragana":                             {{0x3040, 0x309F}},
+	"Katakana":                             {{0x30A0, 0x30FF}},
+	"Bopomofo":                             {{0x3100, 0x312F}},
+	"HangulCompatibilityJamo":              {{0x3130, 0x318F}},
+	"Kanbun":                               {{0x3190, 0x319F}},
+	"BopomofoExtended":                     {{0x31A0, 0x31BF}},
+	"KatakanaPhoneticExtensions":           {{0x31F0, 0x31FF}},
+	"EnclosedCJKLettersandMonths":          {{0x3200, 0x32FF}},
+	"CJKCompatibility":                     {{0x3300, 0x33FF}},
+	"CJKUnifiedIdeographsExtensionA":       {{0x3400, 0x4DBF}},
+	"YijingHexagramSymbols":                {{0x4DC0, 0x4DFF}},
+	"CJKUnifiedIdeographs":                 {{0x4E00, 0x9FFF}},
+	"YiSyllables":                          {{0xA000, 0xA48F}},
+	"YiRadicals":                           {{0xA490, 0xA4CF}},
+	"HangulSyllables":                      {{0xAC00, 0xD7AF}},
+	"HighSurrogates":                       {{0xD800, 0xDB7F}},
+	"HighPrivateUseSurrogates":             {{0xDB80, 0xDBFF}},
+	"LowSurrogates":                        {{0xDC00, 0xDFFF}},
+	"PrivateUse":                           {{0xE000, 0xF8FF}, {0xF0000, 0x10FFFF}},
+	"PrivateUseArea":                       {{0xE000, 0xF8FF}},
+	"CJKCompatibilityIdeographs":           {{0xF900, 0xFAFF}},
+	"AlphabeticPresentationForms":          {{0xFB00, 0xFB4F}},
+	"ArabicPresentationForms-A":            {{0xFB50, 0xFDFF}},
+	"VariationSelectors":                   {{0xFE00, 0xFE0F}},
+	"CombiningHalfMarks":                   {{0xFE20, 0xFE2F}},
+	"CJKCompatibilityForms":                {{0xFE30, 0xFE4F}},
+	"SmallFormVariants":                    {{0xFE50, 0xFE6F}},
+	"ArabicPresentationForms-B":            {{0xFE70, 0xFEFF}},
+	"HalfwidthandFullwidthForms":           {{0xFF00, 0xFFEF}},
+	"Specials":                             {{0xFFF0, 0xFFFF}},
+	"LinearBSyllabary":                     {{0x10000, 0x1007F}},
+	"LinearBIdeograms":                     {{0x10080, 0x100FF}},
+	"AegeanNumbers":                        {{0x10100, 0x1013F}},
+	"OldItalic":                            {{0x10300, 0x1032F}},
+	"Gothic":                               {{0x10330, 0x1034F}},
+	"Ugaritic":                             {{0x10380, 0x1039F}},
+	"Deseret":                              {{0x10400, 0x1044F}},
+	"Shavian":                              {{0x10450, 0x1047F}},
+	"Osmanya":                              {{0x10480, 0x104AF}},
+	"CypriotSyllabary":                     {{0x10800, 0x1083F}},
+	"ByzantineMusicalSymbols":              {{0x1D000, 0x1D0FF}},
+	"MusicalSymbols":                       {{0x1D100, 0x1D1FF}},
+	"TaiXuanJingSymbols":                   {{0x1D300, 0x1D35F}},
+	"MathematicalAlphanumericSymbols":      {{0x1D400, 0x1D7FF}},
+	"CJKUnifiedIdeographsExtensionB":       {{0x20000, 0x2A6DF}},
+	"CJKCompatibilityIdeographsSupplement": {{0x2F800, 0x2FA1F}},
+	"Tags":                                 {{0xE0000, 0xE007F}},
+	"VariationSelectorsSupplement":         {{0xE0100, 0xE01EF}},
+	"SupplementaryPrivateUseArea-A":        {{0xF0000, 0xFFFFF}},
+	"SupplementaryPrivateUseArea-B":        {{0x100000, 0x10FFFF}},
+}
@@ -0,0 +1,413 @@
+package xsd
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// Resolver finds the schema documents referenced by import, include, redefine and override.
+// base is the resolved location of the referencing schema ("" for the first schema) and
+// location is the schemaLocation as written in the schema. The resolved location is returned
+// so that relative locations in the loaded schema can be resolved in turn
+type Resolver interface {
+	Resolve(base, location string) (resolved string, xsdXML []byte, err error)
+}
+
+// FSResolver resolves schema locations relative to each other within a file system,
+// e.g. os.DirFS("schemas") or an embed.FS
+type FSResolver struct {
+	FS fs.FS
+}
+
+// Resolve reads the schema from the file system
+func (r *FSResolver) Resolve(base, location string) (resolved string, xsdXML []byte, err error) {
+	resolved = resolveLocation(base, location)
+	if xsdXML, err = fs.ReadFile(r.FS, resolved); err != nil {
+		return resolved, nil, fmt.Errorf("could not read schema %s, got %v", resolved, err)
+	}
+	return
+}
+
+// MapResolver resolves schema locations from memory, the key is the location relative to the first schema
+type MapResolver map[string][]byte
+
+// Resolve finds the schema in the map
+func (r MapResolver) Resolve(base, location string) (resolved string, xsdXML []byte, err error) {
+	resolved = resolveLocation(base, location)
+	var inMap bool
+	if xsdXML, inMap = r[resolved]; !inMap {
+		return resolved, nil, fmt.Errorf("could not find schema %s", resolved)
+	}
+	return
+}
+
+// resolveLocation makes location relative to the directory of base, URLs are left as they are
+func resolveLocation(base, location string) string {
+	if strings.Contains(location, "://") || strings.Contains(base, "://") {
+		return location
+	}
+	return path.Clean(path.Join(path.Dir(base), location))
+}
+
+// LoadXSD loads the schema at location then follows every import, include, redefine and override
+// using the resolver. The result is one combined XSD holding the components of all the schema documents,
+// with redefined and overridden components replaced, so it can be walked like a single schema.
+// A redefinition derives from the component it redefines, which is kept with _redefined added to its name.
+// Imports without a schemaLocation are skipped as there is nothing to resolve.
+// When references can't be resolved the combined XSD is returned with the DanglingReferencesError
+func LoadXSD(r Resolver, location string) (xsd *XSD, err error) {
+	l := &loader{resolver: r, loaded: make(map[string]*XSD)}
+	var root *XSD
+	if root, err = l.load("", location, ""); err != nil {
+		return nil, err
+	}
+	return l.combine(root)
+}
+
+type loader struct {
+	resolver Resolver
+	loaded   map[string]*XSD // By resolved location, each document is only loaded once for each namespace it's included into
+	schemas  []*XSD          // In load order
+}
+
+// load reads and parses a schema document and everything it references.
+// A schema without a target namespace takes on the namespace it's included into (a chameleon include),
+// it's loaded again for each namespace so the schemas including it don't share it
+func (l *loader) load(base, location, namespace string) (xsd *XSD, err error) {
+	var resolved string
+	var xsdXML []byte
+	if resolved, xsdXML, err = l.resolver.Resolve(base, location); err != nil {
+		return nil, err
+	}
+	chameleonKey := resolved + "#" + namespace
+	if loaded, inMap := l.loaded[chameleonKey]; inMap && namespace > "" {
+		return loaded, nil
+	}
+	if loaded, inMap := l.loaded[resolved]; inMap && (loaded.TargetNamespace > "" || namespace == "") {
+		return loaded, nil
+	}
+	if xsd, err = NewXSD(xsdXML); err != nil {
+		return nil, fmt.Errorf("could not load schema %s, got %v", resolved, err)
+	}
+	xsd.Location = resolved
+	if xsd.TargetNamespace == "" && namespace > "" {
+		xsd.TargetNamespace = namespace
+		xsd.chameleon = true
+		xsd.resolveQNames()
+		l.loaded[chameleonKey] = xsd
+	} else {
+		l.loaded[resolved] = xsd
+	}
+	l.schemas = append(l.schemas, xsd)
+	for _, i := range xsd.Imports {
+		if i.SchemaLocation == "" {
+			continue
+		}
+		var imported *XSD
+		if imported, err = l.load(resolved, i.SchemaLocation, ""); err != nil {
+			return nil, fmt.Errorf("could not import %s from %s, got %v", i.SchemaLocation, resolved, err)
+		}
+		if imported.TargetNamespace != i.Namespace {
+			return nil, fmt.Errorf("could not import %s from %s, its targetNamespace %q isn't the namespace %q", i.SchemaLocation, resolved, imported.TargetNamespace, i.Namespace)
+		}
+	}
+	for _, i := range xsd.Includes {
+		if err = l.include(xsd, i.SchemaLocation); err != nil {
+			return nil, fmt.Errorf("could not include %s from %s, got %v", i.SchemaLocation, resolved, err)
+		}
+	}
+	for _, r := range xsd.Redefines {
+		if err = l.include(xsd, r.SchemaLocation); err != nil {
+			return nil, fmt.Errorf("could not redefine %s from %s, got %v", r.SchemaLocation, resolved, err)
+		}
+	}
+	for _, o := range xsd.Overrides {
+		if err = l.include(xsd, o.SchemaLocation); err != nil {
+			return nil, fmt.Errorf("could not override %s from %s, got %v", o.SchemaLocation, resolved, err)
+		}
+	}
+	return
+}
+
+// include loads a schema into the namespace of the including schema
+func (l *loader) include(including *XSD, location string) (err error) {
+	_, err = l.load(including.Location, location, including.TargetNamespace)
+	return
+}
+
+// combine puts the components of every loaded schema into a copy of the root schema
+func (l *loader) combine(root *XSD) (xsd *XSD, err error) {
+	combined := *root
+	xsd = &combined
+	xsd.SimpleTypes, xsd.ComplexTypes, xsd.Elements, xsd.Attributes = nil, nil, nil, nil
+	xsd.Groups, xsd.AttributeGroups = nil, nil
+	xsd.Schemas = l.schemas
+	for _, s := range l.schemas {
+		xsd.SimpleTypes = append(xsd.SimpleTypes, s.SimpleTypes...)
+		xsd.ComplexTypes = append(xsd.ComplexTypes, s.ComplexTypes...)
+		xsd.Elements = append(xsd.Elements, s.Elements...)
+		xsd.Attributes = append(xsd.Attributes, s.Attributes...)
+		xsd.Groups = append(xsd.Groups, s.Groups...)
+		xsd.AttributeGroups = append(xsd.AttributeGroups, s.AttributeGroups...)
+	}
+	for _, s := range l.schemas {
+		for _, r := range s.Redefines {
+			for _, st := range r.SimpleTypes {
+				if !xsd.redefineSimpleType(st) {
+					return nil, fmt.Errorf("redefine in %s: simpleType %s is not in %s", s.Location, st.Name, r.SchemaLocation)
+				}
+			}
+			for _, ct := range r.ComplexTypes {
+				if !xsd.redefineComplexType(ct) {
+					return nil, fmt.Errorf("redefine in %s: complexType %s is not in %s", s.Location, ct.Name, r.SchemaLocation)
+				}
+			}
+			for _, g := range r.Groups {
+				if !xsd.redefineGroup(g) {
+					return nil, fmt.Errorf("redefine in %s: group %s is not in %s", s.Location, g.Name, r.SchemaLocation)
+				}
+			}
+			for _, ag := range r.AttributeGroups {
+				if !xsd.redefineAttributeGroup(ag) {
+					return nil, fmt.Errorf("redefine in %s: attributeGroup %s is not in %s", s.Location, ag.Name, r.SchemaLocation)
+				}
+			}
+		}
+		// Override components that don't exist in the overridden schema are ignored
+		for _, o := range s.Overrides {
+			for _, st := range o.SimpleTypes {
+				replaceSimpleType(xsd.SimpleTypes, st)
+			}
+			for _, ct := range o.ComplexTypes {
+				replaceComplexType(xsd.ComplexTypes, ct)
+			}
+			for _, e := range o.Elements {
+				replaceElement(xsd.Elements, e)
+			}
+			for _, a := range o.Attributes {
+				replaceAttribute(xsd.Attributes, a)
+			}
+			for _, g := range o.Groups {
+				replaceGroup(xsd.Groups, g)
+			}
+			for _, ag := range o.AttributeGroups {
+				replaceAttributeGroup(xsd.AttributeGroups, ag)
+			}
+		}
+	}
+	err = xsd.Resolve()
+	return
+}
+
+// redefineSimpleType replaces the simple type with its redefinition, which restricts the original
+func (xsd *XSD) redefineSimpleType(st *SimpleType) bool {
+	qn := QName{Namespace: st.Namespace, Local: st.Name}
+	for i, original := range xsd.SimpleTypes {
+		if original.Name != st.Name || original.Namespace != st.Namespace {
+			continue
+		}
+		if r := st.Restriction; r != nil && r.BaseQName == qn {
+			kept := *original
+			kept.Name = redefinedName(original.Name, func(name string) bool { return xsd.hasType(st.Namespace, name) })
+			r.Base, r.BaseQName.Local = renamePrefixed(r.Base, kept.Name), kept.Name
+			xsd.SimpleTypes = append(xsd.SimpleTypes, &kept)
+		}
+		xsd.SimpleTypes[i] = st
+		return true
+	}
+	return false
+}
+
+// redefineComplexType replaces the complex type with its redefinition, which extends or restricts the original
+func (xsd *XSD) redefineComplexType(ct *ComplexType) bool {
+	qn := QName{Namespace: ct.Namespace, Local: ct.Name}
+	for i, original := range xsd.ComplexTypes {
+		if original.Name != ct.Name || original.Namespace != ct.Namespace {
+			continue
+		}
+		var base *string
+		var baseQName *QName
+		switch cc, sc := ct.ComplexContent, ct.SimpleContent; {
+		case cc != nil && cc.Extension != nil:
+			base, baseQName = &cc.Extension.Base, &cc.Extension.BaseQName
+		case cc != nil && cc.Restriction != nil:
+			base, baseQName = &cc.Restriction.Base, &cc.Restriction.BaseQName
+		case sc != nil && sc.Extension != nil:
+			base, baseQName = &sc.Extension.Base, &sc.Extension.BaseQName
+		case sc != nil && sc.Restriction != nil:
+			base, baseQName = &sc.Restriction.Base, &sc.Restriction.BaseQName
+		}
+		if baseQName != nil && *baseQName == qn {
+			kept := *original
+			kept.Name = redefinedName(original.Name, func(name string) bool { return xsd.hasType(ct.Namespace, name) })
+			*base, baseQName.Local = renamePrefixed(*base, kept.Name), kept.Name
+			xsd.ComplexTypes = append(xsd.ComplexTypes, &kept)
+		}
+		xsd.ComplexTypes[i] = ct
+		return true
+	}
+	return false
+}
+
+// redefineGroup replaces the group with its redefinition, a reference to the group in the redefinition is to the original
+func (xsd *XSD) redefineGroup(g *Group) bool {
+	qn := QName{Namespace: g.Namespace, Local: g.Name}
+	for i, original := range xsd.Groups {
+		if original.Name != g.Name || original.Namespace != g.Namespace {
+			continue
+		}
+		var kept *Group
+		_ = g.applyFunction(func(xe XsdElement) error {
+			if ref, isGroup := xe.(*Group); isGroup && ref.Ref > "" && ref.RefQName == qn {
+				if kept == nil {
+					copied := *original
+					copied.Name = redefinedName(original.Name, func(name string) bool {
+						for _, existing := range xsd.Groups {
+							if existing.Name == name && existing.Namespace == g.Namespace {
+								return true
+							}
+						}
+						return false
+					})
+					kept = &copied
+				}
+				ref.Ref, ref.RefQName.Local = renamePrefixed(ref.Ref, kept.Name), kept.Name
+			}
+			return nil
+		})
+		if kept != nil {
+			xsd.Groups = append(xsd.Groups, kept)
+		}
+		xsd.Groups[i] = g
+		return true
+	}
+	return false
+}
+
+// redefineAttributeGroup replaces the attribute group with its redefinition, a reference to the attribute group
+// in the redefinition is to the original
+func (xsd *XSD) redefineAttributeGroup(ag *AttributeGroup) bool {
+	qn := QName{Namespace: ag.Namespace, Local: ag.Name}
+	for i, original := range xsd.AttributeGroups {
+		if original.Name != ag.Name || original.Namespace != ag.Namespace {
+			continue
+		}
+		var kept *AttributeGroup
+		for _, ref := range ag.AttributeGroups {
+			if ref.Ref == "" || ref.RefQName != qn {
+				continue
+			}
+			if kept == nil {
+				copied := *original
+				copied.Name = redefinedName(original.Name, func(name string) bool {
+					for _, existing := range xsd.AttributeGroups {
+						if existing.Name == name && existing.Namespace == ag.Namespace {
+							return true
+						}
+					}
+					return false
+				})
+				kept = &copied
+			}
+			ref.Ref, ref.RefQName.Local = renamePrefixed(ref.Ref, kept.Name), kept.Name
+		}
+		if kept != nil {
+			xsd.AttributeGroups = append(xsd.AttributeGroups, kept)
+		}
+		xsd.AttributeGroups[i] = ag
+		return true
+	}
+	return false
+}
+
+// redefinedName is the name the original of a redefined component is kept under
+func redefinedName(name string, taken func(name string) bool) string {
+	for name += "_redefined"; taken(name); name += "_redefined" {
+	}
+	return name
+}
+
+// renamePrefixed changes the local part of a prefixed name
+func renamePrefixed(prefixed, local string) string {
+	if i := strings.LastIndex(prefixed, ":"); i >= 0 {
+		return prefixed[:i+1] + local
+	}
+	return local
+}
+
+// hasType is true if the combined schema has a simple or complex type with the name
+func (xsd *XSD) hasType(namespace, name string) bool {
+	for _, st := range xsd.SimpleTypes {
+		if st.Name == name && st.Namespace == namespace {
+			return true
+		}
+	}
+	for _, ct := range xsd.ComplexTypes {
+		if ct.Name == name && ct.Namespace == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func replaceSimpleType(simpleTypes []*SimpleType, st *SimpleType) bool {
+	for i, existing := range simpleTypes {
+		if existing.Name == st.Name && existing.Namespace == st.Namespace {
+			simpleTypes[i] = st
+			return true
+		}
+	}
+	return false
+}
+
+func replaceComplexType(complexTypes []*ComplexType, ct *ComplexType) bool {
+	for i, existing := range complexTypes {
+		if existing.Name == ct.Name && existing.Namespace == ct.Namespace {
+			complexTypes[i] = ct
+			return true
+		}
+	}
+	return false
+}
+
+func replaceElement(elements []*Element, e *Element) bool {
+	for i, existing := range elements {
+		if existing.Name == e.Name && existing.Namespace == e.Namespace {
+			elements[i] = e
+			return true
+		}
+	}
+	return false
+}
+
+func replaceAttribute(attributes []*Attribute, a *Attribute) bool {
+	for i, existing := range attributes {
+		if existing.Name == a.Name && existing.Namespace == a.Namespace {
+			attributes[i] = a
+			return true
+		}
+	}
+	return false
+}
+
+func replaceGroup(groups []*Group, g *Group) bool {
+	for i, existing := range groups {
+		if existing.Name == g.Name && existing.Namespace == g.Namespace {
+			groups[i] = g
+			return true
+		}
+	}
+	return false
+}
+
+func replaceAttributeGroup(attributeGroups []*AttributeGroup, ag *AttributeGroup) bool {
+	for i, existing := range attributeGroups {
+		if existing.Name == ag.Name && existing.Namespace == ag.Namespace {
+			attributeGroups[i] = ag
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+package xsd
+
+import (
+	"strings"
+)
+
+// SubstitutionGroups returns the members of each head element that has any, see SubstitutionGroupMembers
+func (xsd *XSD) SubstitutionGroups() map[*Element][]*Element {
+	groups := make(map[*Element][]*Element)
+	for head := range xsd.symbolTable().substitutions {
+		if members := xsd.SubstitutionGroupMembers(head); len(members) > 0 {
+			groups[head] = members
+		}
+	}
+	return groups
+}
+
+// SubstitutionGroupMembers lists the global elements that can be used in place of the head element.
+// Members of members are included, abstract members are not. A member is left out if the head,
+// or the head's type, blocks substitution or the way the member's type is derived from the head's type
+func (xsd *XSD) SubstitutionGroupMembers(head *Element) (members []*Element) {
+	head = declaration(head)
+	seen := map[*Element]bool{head: true}
+	var collect func(path []*Element)
+	collect = func(path []*Element) {
+		for _, m := range xsd.symbolTable().substitutions[path[len(path)-1]] {
+			if seen[m] {
+				continue
+			}
+			seen[m] = true
+			blocked := false
+			for _, h := range path {
+				if xsd.blocksSubstitution(h, m) {
+					blocked = true
+					break
+				}
+			}
+			if blocked {
+				continue
+			}
+			if !m.Abstract {
+				members = append(members, m)
+			}
+			collect(append(path, m))
+		}
+	}
+	collect([]*Element{head})
+	return
+}
+
+// blocksSubstitution is true if the head doesn't allow the member in its place
+func (xsd *XSD) blocksSubstitution(head, member *Element) bool {
+	block := head.Block
+	if block == "" {
+		block = xsd.BlockDefault
+	}
+	headType, headQName := xsd.typeOf(head)
+	if ct, isComplex := headType.(*ComplexType); isComplex && ct.Block > "" {
+		block += " " + ct.Block
+	}
+	blocked := make(map[string]bool)
+	for _, b := range strings.Fields(block) {
+		if b == "#all" {
+			return true
+		}
+		blocked[b] = true
+	}
+	if blocked["substitution"] {
+		return true
+	}
+	memberType, memberQName := xsd.typeOf(member)
+	methods, derived := xsd.derivationMethods(memberType, memberQName, headType, headQName)
+	if !derived {
+		return true // The member's type has to be derived from the head's type
+	}
+	for method := range methods {
+		if blocked[method] {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf is the type of an element, either a global type with its name or an inline type.
+// An element without a type has the type of its substitution group head, otherwise xs:anyType
+func (xsd *XSD) typeOf(e *Element) (xe XsdElement, qn QName) {
+	e = declaration(e)
+	switch {
+	case e.ComplexType != nil:
+		return e.ComplexType, qn
+	case e.SimpleType != nil:
+		return e.SimpleType, qn
+	case e.Type > "":
+		return xsd.symbolTable().types[e.TypeQName], e.TypeQName
+	case len(e.SubstitutionGroupHeads) > 0 && e.SubstitutionGroupHeads[0] != e:
+		return xsd.typeOf(e.SubstitutionGroupHeads[0])
+	}
+	return nil, QName{Namespace: XMLSchemaNamespace, Local: "anyType"}
+}
+
+// derivationMethods follows the base types from a type to an ancestor type, collecting how each type is derived.
+// Types are matched by name or, for inline types, by pointer. derived is false if the type doesn't derive from the ancestor
+func (xsd *XSD) derivationMethods(xe XsdElement, qn QName, ancestor XsdElement, ancestorQName QName) (methods map[string]bool, derived bool) {
+	methods = make(map[string]bool)
+	seen := make(map[QName]bool)
+	for {
+		if (ancestor != nil && xe == ancestor) || (ancestorQName.Local > "" && qn == ancestorQName) {
+			return methods, true
+		}
+		if seen[qn] && qn.Local > "" {
+			return methods, false // A circular definition
+		}
+		seen[qn] = true
+		method, base := "restriction", QName{}
+		switch t := xe.(type) {
+		case *ComplexType:
+			base = QName{Namespace: XMLSchemaNamespace, Local: "anyType"}
+			for _, ex := range []*Extension{t.ComplexContent.extension(), t.SimpleContent.extension()} {
+				if ex != nil {
+					method, base = "extension", ex.BaseQName
+				}
+			}
+			for _, r := range []*Restriction{t.ComplexContent.restriction(), t.SimpleContent.restriction()} {
+				if r != nil {
+					base = r.BaseQName
+				}
+			}
+		case *SimpleType:
+			base = QName{Namespace: XMLSchemaNamespace, Local: "anySimpleType"}
+			if t.Restriction != nil && t.Restriction.Base > "" {
+				base = t.Restriction.BaseQName
+			}
+		default: // A built in type is derived by restriction from its base in the registry, everything derives from xs:anyType
+			bt, builtin := LookupBuiltinType(qn.Local)
+			if qn.Namespace != XMLSchemaNamespace || !builtin || bt.Base == "" {
+				return methods, ancestorQName == QName{Namespace: XMLSchemaNamespace, Local: "anyType"}
+			}
+			base = QName{Namespace: XMLSchemaNamespace, Local: bt.Base}
+		}
+		methods[method] = true
+		xe, qn = xsd.symbolTable().types[base], base
+	}
+}
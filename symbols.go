@@ -0,0 +1,204 @@
+package xsd
+
+import (
+	"fmt"
+	"strings"
+)
+
+// symbolTable holds the global components of a schema by QName, each kind has its own symbol space
+type symbolTable struct {
+	types           map[QName]XsdElement // *ComplexType or *SimpleType
+	elements        map[QName]*Element
+	attributes      map[QName]*Attribute
+	groups          map[QName]*Group
+	attributeGroups map[QName]*AttributeGroup
+	constraints     map[QName]XsdElement    // *Unique, *Key or *KeyRef, these are declared on elements but named globally
+	substitutions   map[*Element][]*Element // The global elements declaring each head in their substitutionGroup
+}
+
+// DanglingReference is a type, ref or base that doesn't name a global component of the schema
+type DanglingReference struct {
+	Component XsdElement // The component holding the reference, e.g. an Element or Restriction
+	Kind      string     // type, ref, base, itemType, memberType, refer or substitutionGroup
+	Name      QName
+}
+
+// DanglingReferencesError lists every reference that could not be resolved
+type DanglingReferencesError []*DanglingReference
+
+func (e DanglingReferencesError) Error() string {
+	var sSlice []string
+	for _, d := range e {
+		sSlice = append(sSlice, fmt.Sprintf("%s %s in %s", d.Kind, d.Name, d.Component.ToString()))
+	}
+	return fmt.Sprintf("%d unresolved references: %s", len(e), strings.Join(sSlice, "; "))
+}
+
+// Resolve (re)builds the symbol table of the global types, elements, attributes, groups and identity constraints,
+// then points each Element.RefElement, Attribute.RefAttribute, Group.RefGroup, AttributeGroup.RefAttributeGroup,
+// KeyRef.ReferTo and Element.SubstitutionGroupHeads at the component it refers to.
+// Resolve is called by NewXSD and LoadXSD, call it again after changing the model.
+// The error is a DanglingReferencesError listing every type, ref and base that couldn't be found,
+// a type in the XML Schema namespace resolves if it's one of the built in types, see LookupBuiltinType
+func (xsd *XSD) Resolve() (err error) {
+	xsd.symbols = &symbolTable{
+		types:           make(map[QName]XsdElement),
+		elements:        make(map[QName]*Element),
+		attributes:      make(map[QName]*Attribute),
+		groups:          make(map[QName]*Group),
+		attributeGroups: make(map[QName]*AttributeGroup),
+		constraints:     make(map[QName]XsdElement),
+		substitutions:   make(map[*Element][]*Element),
+	}
+	for _, st := range xsd.SimpleTypes {
+		xsd.symbols.types[QName{Namespace: st.Namespace, Local: st.Name}] = st
+	}
+	for _, ct := range xsd.ComplexTypes {
+		xsd.symbols.types[QName{Namespace: ct.Namespace, Local: ct.Name}] = ct
+	}
+	for _, e := range xsd.Elements {
+		xsd.symbols.elements[QName{Namespace: e.Namespace, Local: e.Name}] = e
+	}
+	for _, a := range xsd.Attributes {
+		xsd.symbols.attributes[QName{Namespace: a.Namespace, Local: a.Name}] = a
+	}
+	for _, g := range xsd.Groups {
+		xsd.symbols.groups[QName{Namespace: g.Namespace, Local: g.Name}] = g
+	}
+	for _, ag := range xsd.AttributeGroups {
+		xsd.symbols.attributeGroups[QName{Namespace: ag.Namespace, Local: ag.Name}] = ag
+	}
+	_ = xsd.ApplyFunction(func(xe XsdElement) error {
+		switch t := xe.(type) {
+		case *Unique:
+			xsd.symbols.constraints[QName{Namespace: t.Namespace, Local: t.Name}] = t
+		case *Key:
+			xsd.symbols.constraints[QName{Namespace: t.Namespace, Local: t.Name}] = t
+		case *KeyRef:
+			xsd.symbols.constraints[QName{Namespace: t.Namespace, Local: t.Name}] = t
+		}
+		return nil
+	})
+
+	var dangling DanglingReferencesError
+	checkType := func(xe XsdElement, kind string, name string, qn QName) {
+		if name == "" {
+			return
+		}
+		if _, found := xsd.symbols.types[qn]; found {
+			return
+		}
+		if _, found := LookupBuiltinType(qn.Local); found && qn.Namespace == XMLSchemaNamespace {
+			return
+		}
+		dangling = append(dangling, &DanglingReference{Component: xe, Kind: kind, Name: qn})
+	}
+	fResolve := func(xe XsdElement) error {
+		switch t := xe.(type) {
+		case *Element:
+			t.RefElement = nil
+			if t.Ref > "" {
+				if t.RefElement = xsd.symbols.elements[t.RefQName]; t.RefElement == nil {
+					dangling = append(dangling, &DanglingReference{Component: t, Kind: "ref", Name: t.RefQName})
+				}
+			}
+			checkType(t, "type", t.Type, t.TypeQName)
+			t.SubstitutionGroupHeads = nil
+			for _, qn := range t.SubstitutionGroupQNames {
+				head := xsd.symbols.elements[qn]
+				if head == nil {
+					dangling = append(dangling, &DanglingReference{Component: t, Kind: "substitutionGroup", Name: qn})
+					continue
+				}
+				t.SubstitutionGroupHeads = append(t.SubstitutionGroupHeads, head)
+				xsd.symbols.substitutions[head] = append(xsd.symbols.substitutions[head], t)
+			}
+		case *Attribute:
+			t.RefAttribute = nil
+			if t.Ref > "" && t.RefQName.Namespace != XMLNamespace {
+				if t.RefAttribute = xsd.symbols.attributes[t.RefQName]; t.RefAttribute == nil {
+					dangling = append(dangling, &DanglingReference{Component: t, Kind: "ref", Name: t.RefQName})
+				}
+			}
+			checkType(t, "type", t.Type, t.TypeQName)
+		case *Alternative:
+			checkType(t, "type", t.Type, t.TypeQName)
+		case *Restriction:
+			checkType(t, "base", t.Base, t.BaseQName)
+		case *Extension:
+			checkType(t, "base", t.Base, t.BaseQName)
+		case *List:
+			checkType(t, "itemType", t.ItemType, t.ItemTypeQName)
+		case *Union:
+			for _, qn := range t.MemberTypeQNames {
+				checkType(t, "memberType", qn.Local, qn)
+			}
+		case *Group:
+			t.RefGroup = nil
+			if t.Ref > "" {
+				if t.RefGroup = xsd.symbols.groups[t.RefQName]; t.RefGroup == nil {
+					dangling = append(dangling, &DanglingReference{Component: t, Kind: "ref", Name: t.RefQName})
+				}
+			}
+		case *AttributeGroup:
+			t.RefAttributeGroup = nil
+			if t.Ref > "" {
+				if t.RefAttributeGroup = xsd.symbols.attributeGroups[t.RefQName]; t.RefAttributeGroup == nil {
+					dangling = append(dangling, &DanglingReference{Component: t, Kind: "ref", Name: t.RefQName})
+				}
+			}
+		case *KeyRef: // A keyref refers to a key or unique constraint
+			t.ReferTo = nil
+			switch referTo := xsd.symbols.constraints[t.ReferQName].(type) {
+			case *Key, *Unique:
+				t.ReferTo = referTo
+			default:
+				dangling = append(dangling, &DanglingReference{Component: t, Kind: "refer", Name: t.ReferQName})
+			}
+		}
+		return nil
+	}
+	_ = xsd.ApplyFunction(fResolve)
+	if len(dangling) > 0 {
+		return dangling
+	}
+	return nil
+}
+
+// symbolTable returns the symbol table, building it if Resolve hasn't been called
+func (xsd *XSD) symbolTable() *symbolTable {
+	if xsd.symbols == nil {
+		_ = xsd.Resolve()
+	}
+	return xsd.symbols
+}
+
+// LookupType finds the global ComplexType or SimpleType with the name
+func (xsd *XSD) LookupType(qn QName) (xe XsdElement, found bool) {
+	xe, found = xsd.symbolTable().types[qn]
+	return
+}
+
+// LookupElement finds the global Element with the name
+func (xsd *XSD) LookupElement(qn QName) (e *Element, found bool) {
+	e, found = xsd.symbolTable().elements[qn]
+	return
+}
+
+// LookupAttribute finds the global Attribute with the name
+func (xsd *XSD) LookupAttribute(qn QName) (a *Attribute, found bool) {
+	a, found = xsd.symbolTable().attributes[qn]
+	return
+}
+
+// LookupGroup finds the global Group definition with the name
+func (xsd *XSD) LookupGroup(qn QName) (g *Group, found bool) {
+	g, found = xsd.symbolTable().groups[qn]
+	return
+}
+
+// LookupAttributeGroup finds the global AttributeGroup definition with the name
+func (xsd *XSD) LookupAttributeGroup(qn QName) (ag *AttributeGroup, found bool) {
+	ag, found = xsd.symbolTable().attributeGroups[qn]
+	return
+}
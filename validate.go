@@ -0,0 +1,507 @@
+package xsd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XMLSchemaInstanceNamespace is the namespace of xsi:type, xsi:nil and the schema location attributes
+const XMLSchemaInstanceNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// ValidationError is a way an XML document breaks the schema
+type ValidationError struct {
+	Element   string // The name of the element with the problem
+	Message   string
+	Path      string // Where the element is in the document, e.g. /shiporder/item[3]/price
+	Line      int    // The line and column of the element's start tag, or of the child element that's out of place
+	Column    int
+	Offset    int64      // The byte offset of the start tag in the document
+	Component XsdElement // The schema component that isn't met, e.g. the *Element declaration or the *Restriction with the facet
+}
+
+func (e *ValidationError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("element %s: %s", e.Element, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: element %s: %s", e.Line, e.Column, e.Element, e.Message)
+}
+
+// ValidationErrors lists every problem found in a document
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	var sSlice []string
+	for _, ve := range e {
+		sSlice = append(sSlice, ve.Error())
+	}
+	return fmt.Sprintf("%d validation errors: %s", len(e), strings.Join(sSlice, "; "))
+}
+
+// Unwrap lets errors.As find the first *ValidationError
+func (e ValidationErrors) Unwrap() (errs []error) {
+	for _, ve := range e {
+		errs = append(errs, ve)
+	}
+	return
+}
+
+// Validate checks an XML document against the schema, the structure of the elements, their attributes and the values
+// of simple types. The error is a ValidationErrors listing every problem, or the error reading the document.
+// Identity constraints and assertions aren't checked
+func (xsd *XSD) Validate(r io.Reader) error {
+	var errs ValidationErrors
+	if err := xsd.ValidateStream(r, func(ve *ValidationError) {
+		errs = append(errs, ve)
+	}); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateStream checks an XML document like Validate while it's read, calling report for each problem as it's found.
+// Only the open elements are kept, so the memory used depends on the depth of the document rather than its size.
+// The error is the error reading the document
+func (xsd *XSD) ValidateStream(r io.Reader, report func(*ValidationError)) error {
+	v := &validator{xsd: xsd, contents: make(map[*ComplexType]*ContentModel), automata: make(map[*ContentModel]*automaton), report: report}
+	d := xml.NewDecoder(r)
+	for {
+		line, column := d.InputPos() // Where the next token starts
+		at := location{line: line, column: column, offset: d.InputOffset()}
+		token, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("could not read the document, got %v", err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !v.start(t, at) {
+				if err = d.Skip(); err != nil { // The element's content can't be validated
+					return fmt.Errorf("could not read the document, got %v", err)
+				}
+			}
+		case xml.EndElement:
+			v.end()
+		case xml.CharData:
+			v.text(t)
+		}
+	}
+	return nil
+}
+
+// validator keeps a frame for each open element of the document
+type validator struct {
+	xsd      *XSD
+	frames   []*frame
+	contents map[*ComplexType]*ContentModel
+	automata map[*ContentModel]*automaton
+	report   func(*ValidationError)
+}
+
+// location is where an element is in the document
+type location struct {
+	name         xml.Name
+	path         string
+	line, column int
+	offset       int64
+}
+
+type frame struct {
+	location
+	decl        *Element
+	namespaces  map[string]string // The namespace declarations of the element
+	cm          *ContentModel     // The content of a complex type, nil for a simple type
+	auto        *automaton        // The compiled content model of element content
+	state       *dfaState
+	counts      map[*Element]int // The elements of an all content model seen so far
+	counters    []int            // The repeats of the counted particles of the automaton
+	anyContent  bool             // xs:anyType allows anything
+	simpleType  *SimpleType      // The type of the text, for a simple type or simple content
+	simpleName  QName
+	facets      *Restriction // The restriction of a complex type with simple content
+	hasChildren bool
+	text        strings.Builder
+	badText     bool // Text has been reported in element only content
+	nilled      bool
+	siblings    map[QName]int // The number of child elements of each name so far, for their paths
+}
+
+func (v *validator) errorf(at location, component XsdElement, format string, a ...interface{}) {
+	v.report(&ValidationError{
+		Element:   QName{Namespace: at.name.Space, Local: at.name.Local}.String(),
+		Message:   fmt.Sprintf(format, a...),
+		Path:      at.path,
+		Line:      at.line,
+		Column:    at.column,
+		Offset:    at.offset,
+		Component: component,
+	})
+}
+
+// at is the location of the element with the position of one of its children, for problems found at the child
+func (f *frame) at(child location) location {
+	at := f.location
+	at.line, at.column, at.offset = child.line, child.column, child.offset
+	return at
+}
+
+func (v *validator) top() *frame {
+	if len(v.frames) == 0 {
+		return nil
+	}
+	return v.frames[len(v.frames)-1]
+}
+
+// start finds the declaration of an element and checks its attributes, false if its content can't be validated
+func (v *validator) start(t xml.StartElement, at location) bool {
+	qn := QName{Namespace: t.Name.Space, Local: t.Name.Local}
+	parent := v.top()
+	at.name, at.path = t.Name, "/"+t.Name.Local
+	if parent != nil {
+		if parent.siblings == nil {
+			parent.siblings = make(map[QName]int)
+		}
+		parent.siblings[qn]++
+		at.path = parent.path + at.path
+		if n := parent.siblings[qn]; n > 1 { // The first of its name has no index
+			at.path += fmt.Sprintf("[%d]", n)
+		}
+	}
+	var decl *Element
+	switch {
+	case parent == nil:
+		if decl = v.xsd.symbolTable().elements[qn]; decl == nil {
+			v.errorf(at, nil, "there is no global element declaration for the document element")
+			return false
+		}
+	case parent.anyContent:
+		parent.hasChildren = true
+		if decl = v.xsd.symbolTable().elements[qn]; decl == nil {
+			return false // Anything is allowed, a known element is validated
+		}
+	case parent.auto == nil:
+		v.errorf(parent.at(at), parent.decl, "element %s isn't allowed, the element has a simple type", qn)
+		return false
+	default:
+		parent.hasChildren = true
+		var wildcard *Any
+		if term := v.step(parent, qn); term != nil {
+			decl, wildcard = v.xsd.declarationOf([]XsdElement{term}, qn)
+		} else {
+			// Out of place, the parent stays where it was and a declaration elsewhere in its content still validates the element
+			if decl, wildcard = v.xsd.declarationOf(parent.auto.terms, qn); decl == nil && wildcard == nil {
+				v.errorf(at, parent.decl, "element isn't allowed in %s", QName{Namespace: parent.name.Space, Local: parent.name.Local})
+				return false
+			}
+			v.errorf(parent.at(at), parent.decl, "element %s isn't expected here", qn)
+		}
+		if decl == nil {
+			switch {
+			case wildcard.ProcessContents == "skip":
+				return false
+			}
+			if decl = v.xsd.symbolTable().elements[qn]; decl == nil {
+				if wildcard.ProcessContents != "lax" {
+					v.errorf(at, wildcard, "there is no global element declaration for the element allowed by the wildcard")
+				}
+				return false
+			}
+		}
+	}
+
+	f := &frame{location: at, decl: decl, namespaces: make(map[string]string)}
+	for _, a := range t.Attr {
+		switch {
+		case a.Name.Space == "xmlns":
+			f.namespaces[a.Name.Local] = a.Value
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			f.namespaces[""] = a.Value
+		}
+	}
+	v.frames = append(v.frames, f)
+	if decl.Abstract {
+		v.errorf(at, decl, "element is abstract, a member of its substitution group has to be used")
+	}
+
+	xe, typeQName := v.xsd.typeOf(decl)
+	for _, a := range t.Attr {
+		if a.Name.Space != XMLSchemaInstanceNamespace {
+			continue
+		}
+		switch a.Name.Local {
+		case "nil":
+			if f.nilled = a.Value == "true" || a.Value == "1"; f.nilled && !decl.Nillable {
+				v.errorf(at, decl, "element isn't nillable")
+			}
+		case "type": // The type is replaced by the one named in the document
+			if typeQName = v.resolve(a.Value); typeQName.Namespace == XMLSchemaNamespace {
+				xe = nil
+			} else if xe = v.xsd.symbolTable().types[typeQName]; xe == nil {
+				v.errorf(at, decl, "xsi:type %s isn't a type of the schema", a.Value)
+				v.frames = v.frames[:len(v.frames)-1]
+				return false
+			}
+		}
+	}
+	switch st := xe.(type) {
+	case *ComplexType:
+		v.setContent(f, st)
+	case *SimpleType:
+		f.simpleType = st
+	default:
+		if typeQName.Local == "anyType" {
+			f.anyContent = true
+		} else {
+			f.simpleName = typeQName
+		}
+	}
+	v.checkAttributes(f, t.Attr)
+	return true
+}
+
+// setContent sets the content model of an element with a complex type
+func (v *validator) setContent(f *frame, ct *ComplexType) {
+	cm, found := v.contents[ct]
+	if !found {
+		var err error
+		if cm, err = v.xsd.EffectiveContent(ct); err != nil {
+			v.errorf(f.location, ct, "%v", err)
+			f.anyContent = true
+			return
+		}
+		v.contents[ct] = cm
+	}
+	f.cm = cm
+	if cm.SimpleType.Local > "" || ct.SimpleContent != nil {
+		f.simpleName = cm.SimpleType
+		if st, isSimple := v.xsd.symbolTable().types[cm.SimpleType].(*SimpleType); isSimple {
+			f.simpleType = st
+		}
+		f.facets = ct.SimpleContent.restriction()
+		return
+	}
+	if f.auto = v.automata[cm]; f.auto == nil {
+		f.auto = v.xsd.compileContent(cm)
+		v.automata[cm] = f.auto
+	}
+	f.state = f.auto.start
+	if len(f.auto.counters) > 0 {
+		f.counters = make([]int, len(f.auto.counters))
+	}
+}
+
+// step moves the content of the element on by a child element, the term is the particle the child matches.
+// The term is nil if the child isn't expected here
+func (v *validator) step(f *frame, qn QName) (term XsdElement) {
+	if all := f.auto.all; all != nil {
+		for _, e := range all.Elements {
+			if !v.xsd.matchesElement(e, qn) {
+				continue
+			}
+			if _, maximum := particleOccurs(e); maximum != unbounded && f.counts[e] >= maximum {
+				return nil
+			}
+			if f.counts == nil {
+				f.counts = make(map[*Element]int)
+			}
+			f.counts[e]++
+			return e
+		}
+		return nil
+	}
+	to, term := f.auto.step(f.state, f.counters, qn)
+	if to == nil {
+		return nil
+	}
+	f.state = to
+	return term
+}
+
+// complete is true if the child elements seen make up the whole content of the element
+func (v *validator) complete(f *frame) bool {
+	all := f.auto.all
+	if all == nil {
+		return f.state.final
+	}
+	if minimum, _ := particleOccurs(all); minimum == 0 && len(f.counts) == 0 {
+		return true
+	}
+	for _, e := range all.Elements {
+		if minimum, _ := particleOccurs(e); f.counts[e] < minimum {
+			return false
+		}
+	}
+	return true
+}
+
+// checkAttributes checks the attributes are declared, the required ones are there and their values are of the right type
+func (v *validator) checkAttributes(f *frame, attrs []xml.Attr) {
+	var declared []*Attribute
+	var anyAttribute *AnyAttribute
+	if f.cm != nil {
+		declared, anyAttribute = f.cm.Attributes, f.cm.AnyAttribute
+	}
+	present := make(map[QName]bool)
+	for _, a := range attrs {
+		if a.Name.Space == "xmlns" || (a.Name.Space == "" && a.Name.Local == "xmlns") || a.Name.Space == XMLSchemaInstanceNamespace {
+			continue
+		}
+		qn := QName{Namespace: a.Name.Space, Local: a.Name.Local}
+		present[qn] = true
+		var decl *Attribute
+		for _, d := range declared {
+			if d.QName() == qn {
+				decl = d
+				break
+			}
+		}
+		switch {
+		case f.anyContent:
+		case decl == nil && anyAttribute != nil && v.xsd.wildcardAllows(&Any{Namespace: anyAttribute.Namespace}, qn.Namespace):
+			if global := v.xsd.symbolTable().attributes[qn]; global != nil && anyAttribute.ProcessContents != "skip" {
+				v.checkAttributeValue(f, global, a.Value)
+			}
+		case decl == nil:
+			v.errorf(f.location, f.decl, "attribute %s isn't allowed", qn)
+		case decl.Use == "prohibited":
+			v.errorf(f.location, decl, "attribute %s is prohibited", qn)
+		default:
+			v.checkAttributeValue(f, decl, a.Value)
+		}
+	}
+	for _, d := range declared {
+		if d.Use == "required" && !present[d.QName()] {
+			v.errorf(f.location, d, "attribute %s is required", d.QName())
+		}
+	}
+}
+
+func (v *validator) checkAttributeValue(f *frame, a *Attribute, value string) {
+	d := a
+	if a.Ref > "" && a.RefAttribute != nil {
+		d = a.RefAttribute
+	}
+	for _, problem := range v.xsd.valueProblems(d.SimpleType, d.TypeQName, value) {
+		v.errorf(f.location, problem.componentOr(a), "attribute %s: %s", a.QName(), problem.message)
+	}
+	fixed := a.Fixed
+	if fixed == "" {
+		fixed = d.Fixed
+	}
+	if fixed > "" && v.xsd.normalize(d.SimpleType, d.TypeQName, value) != v.xsd.normalize(d.SimpleType, d.TypeQName, fixed) {
+		v.errorf(f.location, a, "attribute %s has to be %q", a.QName(), fixed)
+	}
+}
+
+func (v *validator) text(t xml.CharData) {
+	f := v.top()
+	if f == nil || f.anyContent {
+		return
+	}
+	if f.cm == nil || f.simpleName.Local > "" {
+		f.text.Write(t)
+		return
+	}
+	if !f.cm.Mixed && !f.badText && strings.TrimSpace(string(t)) > "" {
+		f.badText = true
+		v.errorf(f.location, f.decl, "text isn't allowed, the element only has element content")
+	}
+}
+
+// end checks the content of the element once it's all been read
+func (v *validator) end() {
+	f := v.top()
+	if f == nil {
+		return
+	}
+	v.frames = v.frames[:len(v.frames)-1]
+	switch {
+	case f.anyContent:
+	case f.nilled:
+		if f.hasChildren || strings.TrimSpace(f.text.String()) > "" {
+			v.errorf(f.location, f.decl, "element is nil so has to be empty")
+		}
+	case f.cm == nil || f.simpleName.Local > "":
+		value := f.text.String()
+		if value == "" && (f.decl.Default > "" || f.decl.Fixed > "") {
+			value = f.decl.Default + f.decl.Fixed
+		}
+		for _, problem := range v.xsd.valueProblems(f.simpleType, f.simpleName, value) {
+			v.errorf(f.location, problem.componentOr(f.decl), "%s", problem.message)
+		}
+		if f.facets != nil {
+			for _, problem := range v.xsd.facetProblems(f.facets, v.xsd.normalize(f.simpleType, f.simpleName, value), f.simpleType, f.simpleName) {
+				v.errorf(f.location, problem.component, "%s", problem.message)
+			}
+		}
+		if f.decl.Fixed > "" && v.xsd.normalize(f.simpleType, f.simpleName, value) != v.xsd.normalize(f.simpleType, f.simpleName, f.decl.Fixed) {
+			v.errorf(f.location, f.decl, "element has to be %q", f.decl.Fixed)
+		}
+	case !v.complete(f):
+		v.errorf(f.location, f.decl, "the content is incomplete, more elements are expected")
+	}
+}
+
+// resolve finds the namespace of a prefixed name in the document, e.g. the value of xsi:type
+func (v *validator) resolve(name string) QName {
+	prefix, local, hasPrefix := strings.Cut(name, ":")
+	if !hasPrefix {
+		prefix, local = "", name
+	}
+	for i := len(v.frames) - 1; i >= 0; i-- {
+		if ns, found := v.frames[i].namespaces[prefix]; found {
+			return QName{Namespace: ns, Local: local}
+		}
+	}
+	if prefix == "xml" {
+		return QName{Namespace: XMLNamespace, Local: local}
+	}
+	return QName{Local: local}
+}
+
+// declarationOf finds the declaration of a child element among the terms of a content model,
+// otherwise the wildcard that allows it
+func (xsd *XSD) declarationOf(terms []XsdElement, qn QName) (decl *Element, wildcard *Any) {
+	for _, term := range terms {
+		switch t := term.(type) {
+		case *Element:
+			if xsd.matchesElement(t, qn) {
+				if elementQName(t) == qn {
+					return declaration(t), nil
+				}
+				for _, m := range xsd.SubstitutionGroupMembers(declaration(t)) {
+					if elementQName(m) == qn {
+						return m, nil
+					}
+				}
+			}
+		case *Any:
+			if wildcard == nil && xsd.wildcardAllows(t, qn.Namespace) {
+				wildcard = t
+			}
+		}
+	}
+	return
+}
+
+// matchesElement is true if an element with the name matches the element particle, or a member of its substitution group
+func (xsd *XSD) matchesElement(e *Element, qn QName) bool {
+	if elementQName(e) == qn {
+		return true
+	}
+	if e.Ref == "" {
+		return false
+	}
+	for _, m := range xsd.SubstitutionGroupMembers(declaration(e)) {
+		if elementQName(m) == qn {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,384 @@
+package xsd
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// builtinLexical are the lexical forms of the primitive types, a value has to match the pattern of its type
+// and of each type it's derived from, as well as their pattern facets
+var builtinLexical = map[string]*regexp.Regexp{
+	"boolean":      regexp.MustCompile(`^(true|false|1|0)$`),
+	"decimal":      regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)$`),
+	"float":        regexp.MustCompile(`^([+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?|[+-]?INF|NaN)$`),
+	"double":       regexp.MustCompile(`^([+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?|[+-]?INF|NaN)$`),
+	"duration":     regexp.MustCompile(`^-?P([0-9]+Y)?([0-9]+M)?([0-9]+D)?(T([0-9]+H)?([0-9]+M)?([0-9]+(\.[0-9]+)?S)?)?$`),
+	"dateTime":     regexp.MustCompile(`^-?[0-9]{4,}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"date":         regexp.MustCompile(`^-?[0-9]{4,}-[0-9]{2}-[0-9]{2}(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"time":         regexp.MustCompile(`^[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"gYearMonth":   regexp.MustCompile(`^-?[0-9]{4,}-[0-9]{2}(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"gYear":        regexp.MustCompile(`^-?[0-9]{4,}(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"gMonthDay":    regexp.MustCompile(`^--[0-9]{2}-[0-9]{2}(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"gDay":         regexp.MustCompile(`^---[0-9]{2}(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"gMonth":       regexp.MustCompile(`^--[0-9]{2}(Z|[+-][0-9]{2}:[0-9]{2})?$`),
+	"hexBinary":    regexp.MustCompile(`^([0-9a-fA-F]{2})*$`),
+	"base64Binary": regexp.MustCompile(`^([A-Za-z0-9+/] ?)*=? ?=?$`),
+	"QName":        regexp.MustCompile(`^([\pL_][\pL\pN._\-]*:)?[\pL_][\pL\pN._\-]*$`),
+}
+
+// valueProblem is a reason a value isn't valid, the component is the *Restriction or *Union that doesn't allow it,
+// nil for a built in type
+type valueProblem struct {
+	component XsdElement
+	message   string
+}
+
+// componentOr is the component of the problem, or the declaration of the value for a built in type
+func (p *valueProblem) componentOr(decl XsdElement) XsdElement {
+	if p.component == nil {
+		return decl
+	}
+	return p.component
+}
+
+// valueProblems checks a value against a simple type, either a *SimpleType or a named simple or built in type
+func (xsd *XSD) valueProblems(st *SimpleType, qn QName, value string) []*valueProblem {
+	return xsd.simpleValueProblems(st, qn, xsd.normalize(st, qn, value), make(map[*SimpleType]bool))
+}
+
+func (xsd *XSD) simpleValueProblems(st *SimpleType, qn QName, value string, deriving map[*SimpleType]bool) (problems []*valueProblem) {
+	if st == nil {
+		if qn.Namespace == XMLSchemaNamespace {
+			for _, message := range builtinValueProblems(qn.Local, value) {
+				problems = append(problems, &valueProblem{message: message})
+			}
+			return
+		}
+		var isSimple bool
+		if st, isSimple = xsd.symbolTable().types[qn].(*SimpleType); !isSimple {
+			return nil // Unknown types are reported by Resolve
+		}
+	}
+	if deriving[st] {
+		return nil // Circular types are reported by Check
+	}
+	deriving[st] = true
+	defer delete(deriving, st)
+	switch {
+	case st.Restriction != nil:
+		r := st.Restriction
+		if r.SimpleType != nil {
+			problems = xsd.simpleValueProblems(r.SimpleType, QName{}, value, deriving)
+		} else {
+			problems = xsd.simpleValueProblems(nil, r.BaseQName, value, deriving)
+		}
+		problems = append(problems, xsd.facetProblems(r, value, r.SimpleType, r.BaseQName)...)
+	case st.List != nil:
+		for _, item := range strings.Fields(value) {
+			problems = append(problems, xsd.simpleValueProblems(st.List.SimpleType, st.List.ItemTypeQName, item, deriving)...)
+		}
+	case st.Union != nil:
+		for _, member := range st.Union.MemberTypeQNames {
+			if len(xsd.simpleValueProblems(nil, member, xsd.normalize(nil, member, value), deriving)) == 0 {
+				return nil
+			}
+		}
+		for _, member := range st.Union.SimpleTypes {
+			if len(xsd.simpleValueProblems(member, QName{}, xsd.normalize(member, QName{}, value), deriving)) == 0 {
+				return nil
+			}
+		}
+		problems = append(problems, &valueProblem{st.Union, fmt.Sprintf("value %q isn't valid for any member type of the union", value)})
+	}
+	return
+}
+
+// builtinValueProblems checks the lexical form and implicit facets of a built in type
+func builtinValueProblems(name, value string) (problems []string) {
+	bt, found := LookupBuiltinType(name)
+	if !found {
+		return nil
+	}
+	if bt.IsList() {
+		items := strings.Fields(value)
+		if len(items) == 0 {
+			return []string{fmt.Sprintf("%s needs at least one item", name)}
+		}
+		for _, item := range items {
+			problems = append(problems, builtinValueProblems(bt.ItemType, item)...)
+		}
+		return
+	}
+	for t := bt; t != nil; t = t.BaseType() {
+		if re, found := builtinLexical[t.Name]; found && !re.MatchString(value) {
+			return []string{fmt.Sprintf("value %q isn't a valid %s", value, name)}
+		}
+		if re, _ := compiledPattern(t.Pattern); t.Pattern > "" && re != nil && !re.MatchString(value) {
+			return []string{fmt.Sprintf("value %q isn't a valid %s", value, name)}
+		}
+		if t.Primitive {
+			if _, err := parsePrimitive(t.Name, value); err != nil {
+				return []string{fmt.Sprintf("value %q isn't a valid %s", value, name)}
+			}
+		}
+		if t.MinInclusive > "" {
+			if c, ok := compareNumbers(value, t.MinInclusive); ok && c < 0 {
+				problems = append(problems, fmt.Sprintf("value %s is less than %s, the minimum of %s", value, t.MinInclusive, t.Name))
+			}
+		}
+		if t.MaxInclusive > "" {
+			if c, ok := compareNumbers(value, t.MaxInclusive); ok && c > 0 {
+				problems = append(problems, fmt.Sprintf("value %s is more than %s, the maximum of %s", value, t.MaxInclusive, t.Name))
+			}
+		}
+		if len(problems) > 0 {
+			return
+		}
+	}
+	return
+}
+
+// facetProblems checks a value against the facets of a restriction, the value is normalized.
+// The restricted type is a *SimpleType or a named type, for simple content it's the simple type of the content.
+// The length of a list is the number of items and the bounds are values of the primitive type
+func (xsd *XSD) facetProblems(r *Restriction, value string, st *SimpleType, qn QName) (problems []*valueProblem) {
+	length := utf8.RuneCountInString(value)
+	if xsd.isList(st, qn) {
+		length = len(strings.Fields(value))
+	}
+	bt := xsd.primitiveOf(st, qn)
+	if len(r.Enumerations) > 0 {
+		var v *Value // Enumerated values are compared in the value space of the primitive type, a list by its text
+		if bt != nil && !xsd.isList(st, qn) {
+			v, _ = ParseValue(bt.Name, value)
+		}
+		found := false
+		for _, e := range r.Enumerations {
+			if e.Value == value {
+				found = true
+				break
+			}
+			if v == nil {
+				continue
+			}
+			if ev, err := ParseValue(bt.Name, e.Value); err == nil {
+				if c, ordered := v.Compare(ev); ordered && c == 0 {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			problems = append(problems, &valueProblem{r, fmt.Sprintf("value %q isn't one of the enumerated values", value)})
+		}
+	}
+	if len(r.Patterns) > 0 {
+		matched, checked := false, false
+		for _, p := range r.Patterns {
+			re, err := compiledPattern(p.Value)
+			if err != nil {
+				continue // Reported by Check
+			}
+			checked = true
+			if re.MatchString(value) {
+				matched = true
+				break
+			}
+		}
+		if checked && !matched {
+			problems = append(problems, &valueProblem{r, fmt.Sprintf("value %q doesn't match the pattern", value)})
+		}
+	}
+	checkLength := func(facet string, limit string, ok func(limit int) bool) {
+		if n, err := strconv.Atoi(limit); err == nil && !ok(n) {
+			problems = append(problems, &valueProblem{r, fmt.Sprintf("length %d of value %q doesn't meet %s %d", length, value, facet, n)})
+		}
+	}
+	if r.Length != nil {
+		checkLength("length", r.Length.Value, func(n int) bool { return length == n })
+	}
+	if r.MinLength != nil {
+		checkLength("minLength", r.MinLength.Value, func(n int) bool { return length >= n })
+	}
+	if r.MaxLength != nil {
+		checkLength("maxLength", r.MaxLength.Value, func(n int) bool { return length <= n })
+	}
+	primitive := "decimal" // A union's bounds are taken as numbers
+	if bt != nil {
+		primitive = bt.Name
+	}
+	v, err := ParseValue(primitive, value)
+	checkBound := func(facet, limit string, ok func(c int) bool) {
+		l, limitErr := ParseValue(primitive, limit)
+		if err != nil || limitErr != nil {
+			return // Values that aren't valid are reported by their type
+		}
+		if c, ordered := v.Compare(l); !ordered || !ok(c) {
+			problems = append(problems, &valueProblem{r, fmt.Sprintf("value %s doesn't meet %s %s", value, facet, limit)})
+		}
+	}
+	if r.MinInclusive != nil {
+		checkBound("minInclusive", r.MinInclusive.Value, func(c int) bool { return c >= 0 })
+	}
+	if r.MinExclusive != nil {
+		checkBound("minExclusive", r.MinExclusive.Value, func(c int) bool { return c > 0 })
+	}
+	if r.MaxInclusive != nil {
+		checkBound("maxInclusive", r.MaxInclusive.Value, func(c int) bool { return c <= 0 })
+	}
+	if r.MaxExclusive != nil {
+		checkBound("maxExclusive", r.MaxExclusive.Value, func(c int) bool { return c < 0 })
+	}
+	if r.TotalDigits != nil || r.FractionDigits != nil {
+		total, fraction := decimalDigits(value)
+		if r.TotalDigits != nil {
+			if n, err := strconv.Atoi(r.TotalDigits.Value); err == nil && total > n {
+				problems = append(problems, &valueProblem{r, fmt.Sprintf("value %s has more than %d digits", value, n)})
+			}
+		}
+		if r.FractionDigits != nil {
+			if n, err := strconv.Atoi(r.FractionDigits.Value); err == nil && fraction > n {
+				problems = append(problems, &valueProblem{r, fmt.Sprintf("value %s has more than %d fraction digits", value, n)})
+			}
+		}
+	}
+	return
+}
+
+// patterns caches the compiled pattern facets by their XSD regular expression, with the error if they don't compile
+var patterns sync.Map
+
+type compiled struct {
+	re  *regexp.Regexp
+	err error
+}
+
+func compiledPattern(pattern string) (*regexp.Regexp, error) {
+	if c, found := patterns.Load(pattern); found {
+		return c.(*compiled).re, c.(*compiled).err
+	}
+	re, err := CompileRegexp(pattern)
+	patterns.Store(pattern, &compiled{re: re, err: err})
+	return re, err
+}
+
+// compareNumbers compares two decimal numbers exactly, ok is false if either isn't a number
+func compareNumbers(a, b string) (c int, ok bool) {
+	x, xOK := new(big.Rat).SetString(a)
+	y, yOK := new(big.Rat).SetString(b)
+	if !xOK || !yOK {
+		return 0, false
+	}
+	return x.Cmp(y), true
+}
+
+// decimalDigits counts the significant digits of a decimal and those after the decimal point
+func decimalDigits(value string) (total, fraction int) {
+	value = strings.TrimLeft(value, "+-")
+	whole, frac, _ := strings.Cut(value, ".")
+	whole = strings.TrimLeft(whole, "0")
+	frac = strings.TrimRight(frac, "0")
+	return len(whole) + len(frac), len(frac)
+}
+
+// normalize applies the whiteSpace facet of the type to a value
+func (xsd *XSD) normalize(st *SimpleType, qn QName, value string) string {
+	return applyWhiteSpace(xsd.whiteSpace(st, qn, make(map[*SimpleType]bool)), value)
+}
+
+// applyWhiteSpace normalizes a value for a whiteSpace facet of preserve, replace or collapse
+func applyWhiteSpace(whiteSpace, value string) string {
+	switch whiteSpace {
+	case "replace":
+		return strings.Map(func(r rune) rune {
+			if r == '\t' || r == '\n' || r == '\r' {
+				return ' '
+			}
+			return r
+		}, value)
+	case "collapse":
+		return strings.Join(strings.Fields(value), " ")
+	}
+	return value
+}
+
+// whiteSpace is the whiteSpace facet of a type, the nearest one in its derivation
+func (xsd *XSD) whiteSpace(st *SimpleType, qn QName, deriving map[*SimpleType]bool) string {
+	if st == nil {
+		if qn.Namespace == XMLSchemaNamespace {
+			if bt, found := LookupBuiltinType(qn.Local); found && bt.WhiteSpace > "" {
+				return bt.WhiteSpace
+			}
+			return "preserve"
+		}
+		var isSimple bool
+		if st, isSimple = xsd.symbolTable().types[qn].(*SimpleType); !isSimple {
+			return "preserve"
+		}
+	}
+	if deriving[st] {
+		return "preserve"
+	}
+	deriving[st] = true
+	defer delete(deriving, st)
+	switch {
+	case st.Restriction != nil:
+		if st.Restriction.WhiteSpace != nil {
+			return st.Restriction.WhiteSpace.Value
+		}
+		return xsd.whiteSpace(st.Restriction.SimpleType, st.Restriction.BaseQName, deriving)
+	case st.List != nil:
+		return "collapse"
+	}
+	return "preserve" // A union's members normalize the value themselves
+}
+
+// primitiveOf is the primitive type of a *SimpleType or a named simple or built in type, nil if it doesn't have one
+func (xsd *XSD) primitiveOf(st *SimpleType, qn QName) (primitive *BuiltinType) {
+	switch {
+	case st != nil:
+		primitive, _ = xsd.PrimitiveType(st)
+	case qn.Namespace == XMLSchemaNamespace:
+		if bt, builtin := LookupBuiltinType(qn.Local); builtin {
+			primitive = bt.PrimitiveType()
+		}
+	default:
+		if st, isSimple := xsd.symbolTable().types[qn].(*SimpleType); isSimple {
+			primitive, _ = xsd.PrimitiveType(st)
+		}
+	}
+	return
+}
+
+// isList is true if the type is a list type, so its length is the number of items
+func (xsd *XSD) isList(st *SimpleType, qn QName) bool {
+	for seen := make(map[*SimpleType]bool); ; {
+		if st == nil {
+			if qn.Namespace == XMLSchemaNamespace {
+				bt, found := LookupBuiltinType(qn.Local)
+				return found && bt.IsList()
+			}
+			var isSimple bool
+			if st, isSimple = xsd.symbolTable().types[qn].(*SimpleType); !isSimple {
+				return false
+			}
+		}
+		if seen[st] {
+			return false
+		}
+		seen[st] = true
+		switch {
+		case st.List != nil:
+			return true
+		case st.Restriction != nil:
+			st, qn = st.Restriction.SimpleType, st.Restriction.BaseQName
+		default:
+			return false
+		}
+	}
+}
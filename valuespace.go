@@ -0,0 +1,416 @@
+package xsd
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Value is a value of a built in type in the value space of its primitive type, so values written differently can be
+// compared, e.g. 1.50 and 1.5 or 2024-01-01T12:00:00Z and 2024-01-01T13:00:00+01:00
+type Value struct {
+	Type      string    // The built in type, e.g. positiveInteger
+	Primitive string    // The primitive type, which of the fields below is set. Blank for a list or anySimpleType
+	Items     []*Value  // The items of a built in list type, e.g. IDREFS
+	Text      string    // string and the types derived from it, anyURI and anySimpleType
+	Bool      bool      // boolean
+	Decimal   *big.Rat  // decimal and the integer types
+	Float     float64   // float and double
+	DateTime  *DateTime // dateTime, time, date, gYearMonth, gYear, gMonthDay, gDay and gMonth
+	Duration  *Duration // duration and the XSD 1.1 yearMonthDuration and dayTimeDuration
+	Bytes     []byte    // hexBinary and base64Binary
+	QName     QName     // QName and NOTATION, the namespace depends on the document so only the prefix is known
+	Prefix    string
+}
+
+// DateTime is a date and time, the parts its type doesn't have are zero
+type DateTime struct {
+	Year, Month, Day int      // Year 0 is 1 BCE as in XSD 1.1
+	Hour, Minute     int      // Hour is 24 only for 24:00:00, the end of the day
+	Second           *big.Rat // With its fraction, nil for the types without a time
+	HasTimezone      bool
+	Timezone         int // Minutes east of UTC
+}
+
+// Duration is a number of months and a number of seconds, a negative duration has both negative
+type Duration struct {
+	Months  int
+	Seconds *big.Rat
+}
+
+// ParseValue parses a value of a built in type, the value is checked against the lexical space and the implicit
+// facets of the type and its whitespace is normalized first
+func ParseValue(typeName, lexical string) (v *Value, err error) {
+	bt, found := LookupBuiltinType(typeName)
+	if !found {
+		return nil, fmt.Errorf("%s isn't a built in type", typeName)
+	}
+	value := applyWhiteSpace(bt.WhiteSpace, lexical)
+	if problems := builtinValueProblems(typeName, value); len(problems) > 0 {
+		return nil, errors.New(problems[0])
+	}
+	if bt.IsList() {
+		v = &Value{Type: typeName}
+		for _, item := range strings.Fields(value) {
+			iv, err := ParseValue(bt.ItemType, item)
+			if err != nil {
+				return nil, err
+			}
+			v.Items = append(v.Items, iv)
+		}
+		return
+	}
+	primitive := bt.PrimitiveType()
+	if primitive == nil {
+		return &Value{Type: typeName, Text: value}, nil
+	}
+	if v, err = parsePrimitive(primitive.Name, value); err != nil {
+		return nil, err
+	}
+	v.Type = typeName
+	return
+}
+
+// parsePrimitive parses a value of a primitive type that matches its lexical pattern
+func parsePrimitive(primitive, value string) (v *Value, err error) {
+	v = &Value{Primitive: primitive}
+	switch primitive {
+	case "boolean":
+		v.Bool = value == "true" || value == "1"
+	case "decimal":
+		var ok bool
+		if v.Decimal, ok = new(big.Rat).SetString(value); !ok {
+			return nil, fmt.Errorf("%q isn't a decimal", value)
+		}
+	case "float", "double":
+		bitSize := 64
+		if primitive == "float" {
+			bitSize = 32
+		}
+		if v.Float, err = strconv.ParseFloat(value, bitSize); err != nil && !errors.Is(err, strconv.ErrRange) {
+			return nil, fmt.Errorf("%q isn't a %s", value, primitive)
+		}
+		err = nil // Too large a number is INF
+	case "duration":
+		v.Duration, err = parseDuration(value)
+	case "dateTime", "time", "date", "gYearMonth", "gYear", "gMonthDay", "gDay", "gMonth":
+		v.DateTime, err = parseDateTime(primitive, value)
+	case "hexBinary":
+		v.Bytes, err = hex.DecodeString(value)
+	case "base64Binary":
+		v.Bytes, err = base64.StdEncoding.DecodeString(strings.ReplaceAll(value, " ", ""))
+	case "QName", "NOTATION":
+		if !builtinLexical["QName"].MatchString(value) {
+			return nil, fmt.Errorf("%q isn't a qualified name", value)
+		}
+		if prefix, local, hasPrefix := strings.Cut(value, ":"); hasPrefix {
+			v.Prefix, v.QName = prefix, QName{Local: local}
+		} else {
+			v.QName = QName{Local: value}
+		}
+	default: // string and anyURI
+		v.Text = value
+	}
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+var durationParts = regexp.MustCompile(`^(-)?P(?:([0-9]+)Y)?(?:([0-9]+)M)?(?:([0-9]+)D)?(T(?:([0-9]+)H)?(?:([0-9]+)M)?(?:([0-9]+(?:\.[0-9]+)?)S)?)?$`)
+
+func parseDuration(value string) (*Duration, error) {
+	parts := durationParts.FindStringSubmatch(value)
+	switch {
+	case parts == nil:
+		return nil, fmt.Errorf("%q isn't a duration", value)
+	case parts[2]+parts[3]+parts[4]+parts[5] == "":
+		return nil, fmt.Errorf("duration %q doesn't have a number", value)
+	case parts[5] == "T":
+		return nil, fmt.Errorf("duration %q doesn't have a number after T", value)
+	}
+	number := func(s string, unit int64) *big.Rat {
+		n := new(big.Rat) // Blank is 0
+		if s > "" {
+			n.SetString(s)
+		}
+		return n.Mul(n, big.NewRat(unit, 1))
+	}
+	months := number(parts[2], 12)
+	months.Add(months, number(parts[3], 1))
+	if !months.IsInt() || !months.Num().IsInt64() || months.Num().Int64() > math.MaxInt32 {
+		return nil, fmt.Errorf("duration %q has too many years", value)
+	}
+	d := &Duration{Months: int(months.Num().Int64()), Seconds: new(big.Rat)}
+	d.Seconds.Add(d.Seconds, number(parts[4], 86400))
+	d.Seconds.Add(d.Seconds, number(parts[6], 3600))
+	d.Seconds.Add(d.Seconds, number(parts[7], 60))
+	d.Seconds.Add(d.Seconds, number(parts[8], 1))
+	if parts[1] == "-" {
+		d.Months = -d.Months
+		d.Seconds.Neg(d.Seconds)
+	}
+	return d, nil
+}
+
+// parseDateTime parses the date and time types, which have a timezone at the end if they have one
+func parseDateTime(primitive, value string) (dt *DateTime, err error) {
+	dt = &DateTime{}
+	invalid := fmt.Errorf("%q isn't a valid %s", value, primitive)
+	if n := len(value); strings.HasSuffix(value, "Z") {
+		dt.HasTimezone, value = true, value[:n-1]
+	} else if n >= 6 && strings.ContainsRune("+-", rune(value[n-6])) && value[n-3] == ':' {
+		hours, hErr := strconv.Atoi(value[n-5 : n-3])
+		minutes, mErr := strconv.Atoi(value[n-2:])
+		if hErr != nil || mErr != nil || minutes > 59 || hours > 14 || (hours == 14 && minutes > 0) {
+			return nil, fmt.Errorf("%q doesn't have a timezone between -14:00 and +14:00", value)
+		}
+		if dt.HasTimezone, dt.Timezone = true, hours*60+minutes; value[n-6] == '-' {
+			dt.Timezone = -dt.Timezone
+		}
+		value = value[:n-6]
+	}
+	atoi := func(s string) int {
+		n, e := strconv.Atoi(s)
+		if e != nil {
+			err = invalid
+		}
+		return n
+	}
+	year := func(s string) int {
+		if digits := strings.TrimPrefix(s, "-"); len(digits) > 4 && digits[0] == '0' {
+			err = fmt.Errorf("year %s has a leading zero", s)
+		}
+		return atoi(s)
+	}
+	date := func(s string) {
+		i := strings.LastIndexByte(s, '-')
+		j := strings.LastIndexByte(s[:max(i, 0)], '-')
+		if j <= 0 {
+			err = invalid
+			return
+		}
+		dt.Year, dt.Month, dt.Day = year(s[:j]), atoi(s[j+1:i]), atoi(s[i+1:])
+	}
+	clock := func(s string) {
+		parts := strings.Split(s, ":")
+		if len(parts) != 3 {
+			err = invalid
+			return
+		}
+		dt.Hour, dt.Minute = atoi(parts[0]), atoi(parts[1])
+		var ok bool
+		if dt.Second, ok = new(big.Rat).SetString(parts[2]); !ok {
+			err = invalid
+		}
+	}
+	switch primitive {
+	case "dateTime":
+		d, t, _ := strings.Cut(value, "T")
+		date(d)
+		clock(t)
+	case "date":
+		date(value)
+	case "time":
+		clock(value)
+	case "gYearMonth":
+		i := strings.LastIndexByte(value, '-')
+		dt.Year, dt.Month = year(value[:max(i, 0)]), atoi(value[i+1:])
+	case "gYear":
+		dt.Year = year(value)
+	case "gMonthDay":
+		dt.Month, dt.Day = atoi(value[2:4]), atoi(value[5:])
+	case "gDay":
+		dt.Day = atoi(value[3:])
+	case "gMonth":
+		dt.Month = atoi(value[2:])
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hasYear, hasMonth, hasDay := dt.parts(primitive)
+	switch {
+	case hasMonth && (dt.Month < 1 || dt.Month > 12):
+		return nil, fmt.Errorf("month %d isn't between 1 and 12", dt.Month)
+	case hasDay && dt.Day < 1:
+		return nil, fmt.Errorf("day %d isn't a day of the month", dt.Day)
+	case hasDay && hasYear && dt.Day > daysInMonth(dt.Year, dt.Month):
+		return nil, fmt.Errorf("day %d isn't a day of month %d of %d", dt.Day, dt.Month, dt.Year)
+	case hasDay && hasMonth && dt.Day > daysInMonth(2000, dt.Month): // A leap year, --02-29 is allowed
+		return nil, fmt.Errorf("day %d isn't a day of month %d", dt.Day, dt.Month)
+	case hasDay && dt.Day > 31:
+		return nil, fmt.Errorf("day %d isn't a day of the month", dt.Day)
+	}
+	if dt.Second != nil {
+		if dt.Hour > 24 || dt.Minute > 59 || dt.Second.Cmp(big.NewRat(60, 1)) >= 0 ||
+			(dt.Hour == 24 && (dt.Minute > 0 || dt.Second.Sign() > 0)) {
+			return nil, fmt.Errorf("%s isn't a time of day", value)
+		}
+		if primitive == "time" && dt.Hour == 24 { // The end of the day is the start of the next one
+			dt.Hour = 0
+		}
+	}
+	return
+}
+
+// parts are the parts of the date a type has, the types with a time have all of them
+func (dt *DateTime) parts(primitive string) (hasYear, hasMonth, hasDay bool) {
+	switch primitive {
+	case "dateTime", "date":
+		return true, true, true
+	case "gYearMonth":
+		return true, true, false
+	case "gYear":
+		return true, false, false
+	case "gMonthDay":
+		return false, true, true
+	case "gMonth":
+		return false, true, false
+	case "gDay":
+		return false, false, true
+	}
+	return
+}
+
+func daysInMonth(year, month int) int {
+	switch month {
+	case 2:
+		if year%4 == 0 && (year%100 != 0 || year%400 == 0) {
+			return 29
+		}
+		return 28
+	case 4, 6, 9, 11:
+		return 30
+	}
+	return 31
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// timeline is the number of seconds from 0001-01-01T00:00:00Z, as in XSD 1.1. The parts the type doesn't have are
+// those of 1972-12-31T00:00:00, the timezone is given so a value without one can be placed at either end of the day
+func (dt *DateTime) timeline(primitive string, timezone int) *big.Rat {
+	hasYear, hasMonth, hasDay := dt.parts(primitive)
+	yr, mo := 1971, 12
+	if hasYear {
+		yr = dt.Year - 1
+	}
+	if hasMonth {
+		mo = dt.Month
+	}
+	da := daysInMonth(yr+1, mo) - 1
+	if hasDay {
+		da = dt.Day - 1
+	}
+	days := 365*yr + floorDiv(yr, 4) - floorDiv(yr, 100) + floorDiv(yr, 400) + da
+	for m := 1; m < mo; m++ {
+		days += daysInMonth(yr+1, m)
+	}
+	seconds := big.NewRat(int64(days)*86400+int64(dt.Hour)*3600+int64(dt.Minute)*60-int64(timezone)*60, 1)
+	if dt.Second != nil {
+		seconds.Add(seconds, dt.Second)
+	}
+	return seconds
+}
+
+// compareDateTimes orders two values of the same date or time type. A value without a timezone is somewhere between
+// 14 hours ahead and 14 hours behind UTC, so its order with one that has a timezone is only known outside that
+func compareDateTimes(primitive string, a, b *DateTime) (c int, ok bool) {
+	switch {
+	case a.HasTimezone == b.HasTimezone:
+		return a.timeline(primitive, a.Timezone).Cmp(b.timeline(primitive, b.Timezone)), true
+	case !a.HasTimezone:
+		c, ok = compareDateTimes(primitive, b, a)
+		return -c, ok
+	}
+	instant := a.timeline(primitive, a.Timezone)
+	if instant.Cmp(b.timeline(primitive, 14*60)) < 0 {
+		return -1, true
+	}
+	if instant.Cmp(b.timeline(primitive, -14*60)) > 0 {
+		return 1, true
+	}
+	return 0, false
+}
+
+// durationReferences are the dateTimes durations are added to, the order of two durations is known if it's the same
+// for each of them
+var durationReferences = []*DateTime{
+	{Year: 1696, Month: 9, Day: 1, Second: new(big.Rat), HasTimezone: true},
+	{Year: 1697, Month: 2, Day: 1, Second: new(big.Rat), HasTimezone: true},
+	{Year: 1903, Month: 3, Day: 1, Second: new(big.Rat), HasTimezone: true},
+	{Year: 1903, Month: 7, Day: 1, Second: new(big.Rat), HasTimezone: true},
+}
+
+// compareDurations orders two durations, e.g. P1M and P30D aren't ordered as a month can have 28 to 31 days
+func compareDurations(a, b *Duration) (c int, ok bool) {
+	if a.Months == b.Months {
+		return a.Seconds.Cmp(b.Seconds), true
+	}
+	for i, ref := range durationReferences {
+		refC := addDuration(ref, a).Cmp(addDuration(ref, b))
+		if i > 0 && refC != c {
+			return 0, false
+		}
+		c = refC
+	}
+	return c, true
+}
+
+// addDuration is the timeline of a reference dateTime, which is the first of a month, with a duration added
+func addDuration(ref *DateTime, d *Duration) *big.Rat {
+	months := ref.Year*12 + ref.Month - 1 + d.Months
+	dt := *ref
+	dt.Year = floorDiv(months, 12)
+	dt.Month = months - dt.Year*12 + 1
+	t := dt.timeline("dateTime", 0)
+	return t.Add(t, d.Seconds)
+}
+
+// Compare orders two values, c is negative, zero or positive as v is less than, equal to or more than w. ok is false
+// if there's no order between them: they have different primitive types, either is a float NaN, the order of a date
+// with and one without a timezone isn't known or two durations with months and days can't be ordered.
+// Values of types without an order, e.g. strings, are only compared if they are equal
+func (v *Value) Compare(w *Value) (c int, ok bool) {
+	if v.Primitive != w.Primitive || v.Primitive == "" {
+		return 0, false
+	}
+	switch v.Primitive {
+	case "decimal":
+		return v.Decimal.Cmp(w.Decimal), true
+	case "float", "double":
+		switch {
+		case math.IsNaN(v.Float) || math.IsNaN(w.Float):
+			return 0, math.IsNaN(v.Float) && math.IsNaN(w.Float) // NaN is only equal to itself
+		case v.Float < w.Float:
+			return -1, true
+		case v.Float > w.Float:
+			return 1, true
+		}
+		return 0, true
+	case "duration":
+		return compareDurations(v.Duration, w.Duration)
+	case "dateTime", "time", "date", "gYearMonth", "gYear", "gMonthDay", "gDay", "gMonth":
+		return compareDateTimes(v.Primitive, v.DateTime, w.DateTime)
+	case "boolean":
+		return 0, v.Bool == w.Bool
+	case "hexBinary", "base64Binary":
+		return 0, string(v.Bytes) == string(w.Bytes)
+	case "QName", "NOTATION":
+		return 0, v.QName == w.QName && v.Prefix == w.Prefix
+	}
+	return 0, v.Text == w.Text
+}
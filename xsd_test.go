@@ -4,8 +4,11 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"math/big"
 	"os"
 	"path"
+	"runtime"
 	"strings"
 	"testing"
 	"xsd"
@@ -165,8 +168,947 @@ func TestXML(t *testing.T) {
 	}
 }
 
+// loadXSD reads a schema file and unmarshalls it
+func loadXSD(t *testing.T, name string) *xsd.XSD {
+	t.Helper()
+	xsdXML, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("could not read the XML file, got %v", err)
+	}
+	return parseXSD(t, string(xsdXML))
+}
+
+// parseXSD unmarshalls a schema
+func parseXSD(t *testing.T, xsdXML string) *xsd.XSD {
+	t.Helper()
+	x, err := xsd.NewXSD([]byte(xsdXML))
+	if err != nil {
+		t.Fatalf("could not unmarshal XML into XSD, got %v", err)
+	}
+	return x
+}
+
+// TestLoadXSD loads a schema made of several files and checks the combined result
+func TestLoadXSD(t *testing.T) {
+	combined, err := xsd.LoadXSD(&xsd.FSResolver{FS: os.DirFS("./xsd/multi")}, "order.xsd")
+	if !assert.NoError(t, err, "loading multi file schema") {
+		return
+	}
+	assert.Len(t, combined.Schemas, 5)
+	assert.Len(t, combined.ComplexTypes, 2)
+	assert.Len(t, combined.SimpleTypes, 4) // common.xsd is included into both namespaces, size is redefined
+	for _, st := range combined.SimpleTypes {
+		switch st.Name {
+		case "size":
+			assert.Len(t, st.Restriction.Enumerations, 2, "size should be redefined")
+			assert.Equal(t, "size_redefined", st.Restriction.BaseQName.Local, "size restricts the original")
+		case "size_redefined":
+			assert.Len(t, st.Restriction.Enumerations, 3)
+		}
+	}
+	assert.Empty(t, combined.Check())
+	order := func(size string) io.Reader {
+		return strings.NewReader("<order><orderperson>Ann</orderperson><shipto><street>Main St</street><city>Leeds</city></shipto><size>" + size + "</size></order>")
+	}
+	assert.NoError(t, combined.Validate(order("small")))
+	assert.Error(t, combined.Validate(order("medium")), "removed by the redefinition")
+	assert.Error(t, combined.Validate(order("huge")), "not in the original")
+	messages, err := combined.Messages("protobuf")
+	if assert.NoError(t, err) {
+		var names []string
+		for _, m := range messages {
+			names = append(names, m.Name)
+		}
+		assert.ElementsMatch(t, []string{"order", "ordertype", "addresstype", "stringtype", "size", "size_redefined"}, names)
+	}
+
+	// The same schemas from memory
+	resolver := xsd.MapResolver{}
+	for _, location := range []string{"order.xsd", "address.xsd", "types/common.xsd", "types/sizes.xsd"} {
+		if resolver[location], err = os.ReadFile(path.Join("./xsd/multi", location)); err != nil {
+			t.Fatalf("could not read %s, got %v", location, err)
+		}
+	}
+	var fromMap *xsd.XSD
+	if fromMap, err = xsd.LoadXSD(resolver, "order.xsd"); assert.NoError(t, err, "loading from map") {
+		assert.Equal(t, combined.ItemsString(), fromMap.ItemsString())
+	}
+
+	delete(resolver, "types/sizes.xsd")
+	_, err = xsd.LoadXSD(resolver, "order.xsd")
+	assert.Error(t, err, "missing redefined schema")
+
+	// Groups and complex types redefined in terms of themselves, and a schema included into two namespaces
+	resolver = xsd.MapResolver{
+		"a.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:a" xmlns="urn:a">
+			<xs:import namespace="urn:b" schemaLocation="b.xsd"/>
+			<xs:redefine schemaLocation="common.xsd">
+				<xs:group name="names">
+					<xs:sequence>
+						<xs:group ref="names"/>
+						<xs:element name="nickname" type="name"/>
+					</xs:sequence>
+				</xs:group>
+				<xs:complexType name="person">
+					<xs:complexContent>
+						<xs:extension base="person">
+							<xs:attribute name="id" type="xs:string"/>
+						</xs:extension>
+					</xs:complexContent>
+				</xs:complexType>
+			</xs:redefine>
+			<xs:element name="person" type="person"/>
+		</xs:schema>`),
+		"b.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:b" xmlns="urn:b">
+			<xs:include schemaLocation="common.xsd"/>
+			<xs:element name="person" type="person"/>
+		</xs:schema>`),
+		"common.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+			<xs:simpleType name="name"><xs:restriction base="xs:string"/></xs:simpleType>
+			<xs:group name="names"><xs:sequence><xs:element name="given" type="name"/></xs:sequence></xs:group>
+			<xs:complexType name="person"><xs:sequence><xs:group ref="names"/></xs:sequence></xs:complexType>
+		</xs:schema>`),
+	}
+	if combined, err = xsd.LoadXSD(resolver, "a.xsd"); !assert.NoError(t, err, "loading redefinitions") {
+		return
+	}
+	assert.NoError(t, combined.Resolve())
+	assert.Empty(t, combined.Check())
+	var persons []string
+	for _, ct := range combined.ComplexTypes {
+		persons = append(persons, ct.Namespace+" "+ct.Name)
+	}
+	assert.ElementsMatch(t, []string{"urn:a person", "urn:a person_redefined", "urn:b person"}, persons, "common.xsd is also included into urn:b")
+	names, found := combined.LookupGroup(xsd.QName{Namespace: "urn:a", Local: "names"})
+	if assert.True(t, found) {
+		assert.Equal(t, "names_redefined", names.Sequence.Groups[0].RefGroup.Name, "the reference in the redefinition is to the original")
+	}
+	assert.NoError(t, combined.Validate(strings.NewReader(`<a:person xmlns:a="urn:a" id="1"><given>Ann</given><nickname>Annie</nickname></a:person>`)))
+	assert.Error(t, combined.Validate(strings.NewReader(`<a:person xmlns:a="urn:a" id="1"><given>Ann</given></a:person>`)))
+	assert.NoError(t, combined.Validate(strings.NewReader(`<b:person xmlns:b="urn:b"><given>Ann</given></b:person>`)), "urn:b isn't redefined")
+
+	// A prefix declared in an imported schema only, its types are still local
+	resolver = xsd.MapResolver{
+		"a.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:a" xmlns:c="urn:c">
+			<xs:import namespace="urn:c" schemaLocation="c.xsd"/>
+			<xs:element name="person" type="c:person"/>
+		</xs:schema>`),
+		"c.xsd": []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:c" xmlns:cc="urn:c">
+			<xs:simpleType name="str"><xs:restriction base="xs:string"/></xs:simpleType>
+			<xs:complexType name="person"><xs:sequence><xs:element name="name" type="cc:str"/></xs:sequence></xs:complexType>
+		</xs:schema>`),
+	}
+	if combined, err = xsd.LoadXSD(resolver, "a.xsd"); !assert.NoError(t, err, "loading an import") {
+		return
+	}
+	if messages, err = combined.Messages("protobuf"); assert.NoError(t, err) {
+		for _, m := range messages {
+			assert.Empty(t, m.Package, "%s is a message of the schema", m.Name)
+			for _, mi := range m.MessageItems {
+				if mi.Name == "name" {
+					assert.Equal(t, "str", mi.Type)
+				}
+			}
+		}
+	}
+
+	// The imported schema has to be in the namespace of the import, references that can't be resolved are reported
+	resolver["b.xsd"] = []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:b">
+		<xs:import namespace="urn:b" schemaLocation="c.xsd"/>
+	</xs:schema>`)
+	_, err = xsd.LoadXSD(resolver, "b.xsd")
+	assert.EqualError(t, err, `could not import c.xsd from b.xsd, its targetNamespace "urn:c" isn't the namespace "urn:b"`)
+	resolver["b.xsd"] = []byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema" targetNamespace="urn:b" xmlns:c="urn:c">
+		<xs:import namespace="urn:c" schemaLocation="c.xsd"/>
+		<xs:element name="person" type="c:persontype"/>
+	</xs:schema>`)
+	combined, err = xsd.LoadXSD(resolver, "b.xsd")
+	var dangling xsd.DanglingReferencesError
+	if assert.ErrorAs(t, err, &dangling) && assert.NotNil(t, combined) {
+		assert.Len(t, dangling, 1)
+		assert.Len(t, combined.Schemas, 2)
+	}
+}
+
+// TestQNames checks type names are resolved using the namespace declarations of the schema
+func TestQNames(t *testing.T) {
+	x := loadXSD(t, "./xsd/namespaces.xsd")
+	po := "http://example.com/po"
+	assert.Equal(t, po, x.Namespaces()[""])
+	assert.Equal(t, xsd.XMLSchemaNamespace, x.Namespaces()["xsd"])
+	assert.Equal(t, xsd.QName{Namespace: xsd.XMLSchemaNamespace, Local: "string"}, x.SimpleTypes[0].Restriction.BaseQName)
+	elements := x.ComplexTypes[0].Sequence.Elements
+	assert.Equal(t, xsd.QName{Namespace: po, Local: "sku"}, elements[0].TypeQName)
+	assert.Equal(t, po, elements[0].Namespace, "elementFormDefault is qualified")
+	assert.Equal(t, "", elements[2].Namespace, "form is unqualified")
+	assert.Equal(t, "", x.ComplexTypes[0].Attributes[0].Namespace)
+	assert.Equal(t, xsd.QName{Namespace: po, Local: "item"}, x.Elements[0].TypeQName, "default namespace")
+	assert.Equal(t, "{http://example.com/po}item", x.Elements[0].TypeQName.String())
+}
+
+// TestLookup checks global components can be found and refs point at them
+func TestLookup(t *testing.T) {
+	x := loadXSD(t, "./xsd/shiporder_elm.xsd")
+	assert.NoError(t, x.Resolve())
+	shiporder, found := x.LookupElement(xsd.QName{Local: "shiporder"})
+	if assert.True(t, found) {
+		orderperson := shiporder.ComplexType.Sequence.Elements[0]
+		assert.Equal(t, "orderperson", orderperson.RefElement.Name)
+		assert.Equal(t, "orderid", shiporder.ComplexType.Attributes[0].RefAttribute.Name)
+	}
+	_, found = x.LookupAttribute(xsd.QName{Local: "orderid"})
+	assert.True(t, found)
+	_, found = x.LookupElement(xsd.QName{Namespace: "http://example.com", Local: "shiporder"})
+	assert.False(t, found, "wrong namespace")
+
+	x, err := xsd.NewXSD([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:complexType name="a"><xs:sequence>
+			<xs:element name="b" type="missing"/>
+			<xs:element ref="c"/>
+			<xs:element name="d" type="xs:notAType"/>
+		</xs:sequence></xs:complexType>
+	</xs:schema>`))
+	if assert.NoError(t, err) {
+		_, found = x.LookupType(xsd.QName{Local: "a"})
+		assert.True(t, found)
+		err = x.Resolve()
+		var dangling xsd.DanglingReferencesError
+		if assert.ErrorAs(t, err, &dangling) {
+			assert.Len(t, dangling, 3)
+			assert.Contains(t, err.Error(), "type missing in Element: b")
+			assert.Contains(t, err.Error(), "type {http://www.w3.org/2001/XMLSchema}notAType in Element: d", "not a built in type")
+		}
+	}
+
+	x = loadXSD(t, "./xsd/groups.xsd")
+	assert.NoError(t, x.Resolve())
+	custgroup, found := x.LookupGroup(xsd.QName{Local: "custgroup"})
+	if assert.True(t, found) {
+		assert.Same(t, custgroup, x.ComplexTypes[1].Group.RefGroup)
+	}
+	versionattrs, found := x.LookupAttributeGroup(xsd.QName{Local: "versionattrs"})
+	if assert.True(t, found) {
+		assert.Same(t, versionattrs, x.AttributeGroups[0].AttributeGroups[0].RefAttributeGroup)
+	}
+}
+
+// TestIdentityConstraints checks key, keyref and unique XPaths are followed through the schema
+func TestIdentityConstraints(t *testing.T) {
+	x := loadXSD(t, "./xsd/keys.xsd")
+	assert.NoError(t, x.Resolve())
+	constraints := x.IdentityConstraints()
+	if !assert.Len(t, constraints, 3) {
+		return
+	}
+	order := x.Elements[0]
+	unique, key, keyref := constraints[0], constraints[1], constraints[2]
+	assert.Equal(t, "key", key.Kind)
+	assert.Equal(t, "productKey", key.Name.Local)
+	assert.Same(t, order, key.Element)
+	if assert.Len(t, key.Selected, 1) && assert.Len(t, key.Fields, 1) {
+		assert.Equal(t, "product", key.Selected[0].Name)
+		assert.Equal(t, "code", key.Fields[0].(*xsd.Attribute).Name)
+	}
+	assert.Equal(t, "keyref", keyref.Kind)
+	assert.Same(t, key.Constraint, keyref.Refer)
+	if assert.Len(t, keyref.Selected, 1) && assert.Len(t, keyref.Fields, 1) {
+		assert.Equal(t, "line", keyref.Selected[0].Name)
+		assert.Equal(t, "product", keyref.Fields[0].(*xsd.Element).Name)
+	}
+	assert.Equal(t, "unique", unique.Kind)
+	if assert.Len(t, unique.Selected, 1) && assert.Len(t, unique.Fields, 1) {
+		assert.Equal(t, "number", unique.Fields[0].(*xsd.Attribute).Name)
+	}
+	assert.Contains(t, x.ToStringP(), "KeyRef: lineProduct refer productKey")
+
+	x, err := xsd.NewXSD([]byte(`<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="a" type="xs:string">
+			<xs:keyref name="b" refer="missing"><xs:selector xpath="."/><xs:field xpath="."/></xs:keyref>
+		</xs:element>
+	</xs:schema>`))
+	if assert.NoError(t, err) {
+		assert.ErrorContains(t, x.Resolve(), "refer missing in KeyRef: b")
+	}
+}
+
+// TestSubstitutionGroups checks members are found through member heads and blocked members are left out
+func TestSubstitutionGroups(t *testing.T) {
+	x := loadXSD(t, "./xsd/substitution.xsd")
+	assert.NoError(t, x.Resolve())
+	names := func(elements []*xsd.Element) (sSlice []string) {
+		for _, e := range elements {
+			sSlice = append(sSlice, e.Name)
+		}
+		return
+	}
+	shape, _ := x.LookupElement(xsd.QName{Local: "shape"})
+	assert.Equal(t, []string{"circle", "square"}, names(x.SubstitutionGroupMembers(shape)), "abstract polygon and text of an unrelated type are left out")
+	fixedshape, _ := x.LookupElement(xsd.QName{Local: "fixedshape"})
+	assert.Empty(t, x.SubstitutionGroupMembers(fixedshape), "extension is blocked")
+	label, _ := x.LookupElement(xsd.QName{Local: "label"})
+	caption, _ := x.LookupElement(xsd.QName{Local: "caption"})
+	assert.Equal(t, []*xsd.Element{label}, caption.SubstitutionGroupHeads)
+	groups := x.SubstitutionGroups()
+	assert.Len(t, groups, 3) // shape, polygon and label
+	assert.Equal(t, []string{"caption"}, names(groups[label]))
+}
+
+// TestParticleOrder checks mixed particles keep their document order through unmarshal and marshal
+func TestParticleOrder(t *testing.T) {
+	x := loadXSD(t, "./xsd/particles.xsd")
+	kinds := func(s *xsd.Sequence) (sSlice []string) {
+		for _, p := range s.Particles() {
+			sSlice = append(sSlice, strings.SplitN(p.ToString(), ":", 2)[0])
+		}
+		return
+	}
+	expected := []string{"Element", "Choice", "Element", "Sequence", "Group", "Choice", "Element"}
+	sequence := x.ComplexTypes[0].Sequence
+	assert.Equal(t, expected, kinds(sequence))
+	assert.Len(t, sequence.Elements, 3)
+	assert.Len(t, sequence.Choices, 2)
+	if assert.Len(t, sequence.Choices[0].Particles(), 3) {
+		assert.Len(t, sequence.Choices[0].Sequences[0].Elements, 2)
+	}
+	marshalled, err := xml.Marshal(x)
+	if err != nil {
+		t.Fatalf("could not marshal XSD, got %v", err)
+	}
+	if x, err = xsd.NewXSD(marshalled); err != nil {
+		t.Fatalf("could not unmarshal marshalled XSD, got %v", err)
+	}
+	assert.Equal(t, expected, kinds(x.ComplexTypes[0].Sequence))
+
+	// The typed slices are the particles, their positions only order them
+	sequence = x.ComplexTypes[0].Sequence
+	sequence.Choices = sequence.Choices[:1]
+	sequence.Elements = append(sequence.Elements, &xsd.Element{Name: "added", Type: "xs:string"})
+	if marshalled, err = xml.Marshal(x); err != nil {
+		t.Fatalf("could not marshal XSD, got %v", err)
+	}
+	if x, err = xsd.NewXSD(marshalled); err != nil {
+		t.Fatalf("could not unmarshal marshalled XSD, got %v", err)
+	}
+	sequence = x.ComplexTypes[0].Sequence
+	assert.Equal(t, []string{"Element", "Choice", "Element", "Sequence", "Group", "Element", "Element"}, kinds(sequence))
+	if assert.Len(t, sequence.Elements, 4) {
+		assert.Equal(t, "added", sequence.Elements[3].Name)
+	}
+	assert.Contains(t, strings.Join(x.ItemsString(), "\n"), "added")
+}
+
+// TestAll checks the occurrences of an all and its elements
+func TestAll(t *testing.T) {
+	x := loadXSD(t, "./xsd/all.xsd")
+	all := x.ComplexTypes[0].All
+	if assert.NotNil(t, all) && assert.Len(t, all.Elements, 3) {
+		assert.Equal(t, "", all.MinOccurs)
+		assert.Equal(t, "0", all.Elements[2].MinOccurs)
+	}
+	messages, err := x.Messages("protobuf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	occurs := make(map[string]string)
+	for _, mi := range messages[0].MessageItems {
+		occurs[mi.Name] = mi.MandatoryOptional + mi.MinOccurs + mi.MaxOccurs
+	}
+	assert.Equal(t, map[string]string{"firstname": "", "lastname": "", "nickname": "O0"}, occurs)
+
+	x = parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="person">
+			<xs:complexType>
+				<xs:all minOccurs="0" maxOccurs="1">
+					<xs:element name="firstname" type="xs:string"/>
+					<xs:element name="nickname" type="xs:string" minOccurs="0"/>
+				</xs:all>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`)
+	all = x.Elements[0].ComplexType.All
+	assert.Equal(t, "0", all.MinOccurs)
+	assert.Equal(t, "1", all.MaxOccurs)
+	marshalled, err := xml.Marshal(x)
+	if err != nil {
+		t.Fatalf("could not marshal XSD, got %v", err)
+	}
+	assert.Contains(t, string(marshalled), `minOccurs="0" maxOccurs="1"`)
+	for document, valid := range map[string]bool{
+		"<person/>": true,
+		"<person><nickname>Al</nickname><firstname>Alan</firstname></person>":   true,
+		"<person><nickname>Al</nickname></person>":                              false,
+		"<person><firstname>Alan</firstname><firstname>Al</firstname></person>": false,
+	} {
+		assert.Equal(t, valid, x.Validate(strings.NewReader(document)) == nil, document)
+	}
+}
+
+// TestListUnion checks list and union types are modelled and become repeated items and member types
+func TestListUnion(t *testing.T) {
+	x := loadXSD(t, "./xsd/list_union.xsd")
+	sizelist, colours, size := x.SimpleTypes[0], x.SimpleTypes[1], x.SimpleTypes[2]
+	if assert.NotNil(t, sizelist.List) {
+		assert.Equal(t, xsd.QName{Namespace: xsd.XMLSchemaNamespace, Local: "integer"}, sizelist.List.ItemTypeQName)
+	}
+	if assert.NotNil(t, colours.List) && assert.NotNil(t, colours.List.SimpleType) {
+		assert.Len(t, colours.List.SimpleType.Restriction.Enumerations, 2)
+	}
+	if assert.NotNil(t, size.Union) {
+		assert.Equal(t, []xsd.QName{{Namespace: xsd.XMLSchemaNamespace, Local: "integer"}, {Local: "sizelist"}}, size.Union.MemberTypeQNames)
+		assert.Len(t, size.Union.SimpleTypes, 1)
+	}
+
+	messages, err := x.Messages("protobuf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	items := make(map[string]*xsd.MessageItem)
+	for _, m := range messages {
+		for _, mi := range m.MessageItems {
+			items[m.Name+"."+mi.Name] = mi
+		}
+	}
+	if mi := items["sizelist.sizelist"]; assert.NotNil(t, mi) {
+		assert.True(t, mi.Repeated, "a list is repeated")
+		assert.Equal(t, "int64", mi.Type, "of its item type")
+	}
+	if mi := items["colours.colours"]; assert.NotNil(t, mi) {
+		assert.True(t, mi.Repeated)
+		assert.Equal(t, "string", mi.Type, "an inline item type")
+		assert.Equal(t, []string{"red", "green"}, mi.Values)
+	}
+	if mi := items["shirt.codes"]; assert.NotNil(t, mi) {
+		assert.True(t, mi.Repeated, "an element of an inline list type")
+		assert.Equal(t, "string", mi.Type)
+	}
+	if mi := items["size.size"]; assert.NotNil(t, mi) {
+		assert.False(t, mi.Repeated)
+		assert.Equal(t, []string{"int64", "sizelist", "string"}, mi.MemberTypes, "named then inline members")
+		assert.Equal(t, []string{"small", "large"}, mi.Values)
+	}
+	if mi := items["amount.amount"]; assert.NotNil(t, mi) {
+		assert.Equal(t, "int64", mi.Type, "the members have the same type")
+	}
+	assert.Equal(t, "size", items["shirt.size"].Type)
+}
+
+// TestDeclarations checks nillable, default, fixed, abstract, block and final are modelled and reach the message items
+func TestDeclarations(t *testing.T) {
+	x := loadXSD(t, "./xsd/declarations.xsd")
+	assert.Equal(t, "list union", x.SimpleTypes[0].Final)
+	publicationtype := x.ComplexTypes[0]
+	assert.True(t, publicationtype.Abstract)
+	assert.Equal(t, "restriction", publicationtype.Block)
+	assert.Equal(t, "#all", publicationtype.Final)
+	elements := publicationtype.Sequence.Elements
+	assert.Equal(t, "1", elements[1].Default)
+	assert.True(t, elements[2].Nillable)
+	assert.Equal(t, "GBP", publicationtype.Attributes[0].Fixed)
+	publication, book := x.Elements[0], x.Elements[1]
+	assert.True(t, publication.Abstract)
+	assert.Equal(t, "substitution", publication.Block)
+	assert.Equal(t, "extension", publication.Final)
+	assert.Equal(t, "publication", book.SubstitutionGroup)
+	assert.False(t, book.Abstract)
+
+	marshalled, err := xml.Marshal(x)
+	if err != nil {
+		t.Fatalf("could not marshal XSD, got %v", err)
+	}
+	for _, attr := range []string{`final="list union"`, `abstract="true" block="restriction" final="#all"`, `nillable="true"`, `fixed="GBP"`, `block="substitution" final="extension"`} {
+		assert.Contains(t, string(marshalled), attr)
+	}
+
+	messages, err := x.Messages("protobuf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	items := make(map[string]*xsd.MessageItem)
+	for _, m := range messages {
+		for _, mi := range m.MessageItems {
+			items[m.Name+"."+mi.Name] = mi
+		}
+	}
+	assert.Equal(t, "1", items["publicationtype.edition"].Default)
+	assert.True(t, items["publicationtype.withdrawn"].Nillable)
+	assert.False(t, items["publicationtype.title"].Nillable)
+	assert.Equal(t, "GBP", items["publicationtype.currency"].Fixed)
+	assert.Equal(t, "en", items["publicationtype.lang"].Default, "from the global attribute")
+	assert.Equal(t, "draft", items["publicationtype.status"].Default, "the reference's own default")
+	assert.True(t, items["publication.publication"].Abstract)
+	assert.True(t, items["catalogue.publication"].Abstract, "from the referenced element")
+	assert.False(t, items["book.book"].Abstract)
+}
+
+// TestMixed checks mixed on a complex type and on its complex content
+func TestMixed(t *testing.T) {
+	x := loadXSD(t, "./xsd/mixed.xsd")
+	paragraphtype, clausetype := x.ComplexTypes[0], x.ComplexTypes[1]
+	assert.True(t, paragraphtype.IsMixed())
+	assert.Nil(t, paragraphtype.ComplexContent)
+	assert.False(t, clausetype.Mixed)
+	assert.True(t, clausetype.IsMixed(), "mixed on the complex content")
+	messages, err := x.Messages("protobuf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	mixed := make(map[string]bool)
+	for _, m := range messages {
+		mixed[m.Name] = m.Mixed
+	}
+	assert.Equal(t, map[string]bool{"paragraphtype": true, "clausetype": true, "contract": true, "signature": true}, mixed)
+
+	x = parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:complexType name="base" mixed="true">
+			<xs:sequence><xs:element name="a" type="xs:string"/></xs:sequence>
+		</xs:complexType>
+		<xs:complexType name="unmixed" mixed="true">
+			<xs:complexContent mixed="false"><xs:restriction base="base">
+				<xs:sequence><xs:element name="a" type="xs:string"/></xs:sequence>
+			</xs:restriction></xs:complexContent>
+		</xs:complexType>
+		<xs:complexType name="remixed" mixed="false">
+			<xs:complexContent mixed="true"><xs:extension base="base"/></xs:complexContent>
+		</xs:complexType>
+		<xs:element name="unmixed" type="unmixed"/>
+		<xs:element name="remixed" type="remixed"/>
+	</xs:schema>`)
+	unmixed, remixed := x.ComplexTypes[1], x.ComplexTypes[2]
+	assert.False(t, unmixed.IsMixed(), "complex content mixed=false overrides the complex type")
+	assert.True(t, remixed.IsMixed(), "complex content mixed=true overrides the complex type")
+	assert.Error(t, x.Validate(strings.NewReader("<unmixed>text<a/></unmixed>")))
+	assert.NoError(t, x.Validate(strings.NewReader("<remixed>text<a/></remixed>")))
+	marshalled, err := xml.Marshal(x)
+	if err != nil {
+		t.Fatalf("could not marshal XSD, got %v", err)
+	}
+	assert.Contains(t, string(marshalled), `<complexContent mixed="false">`)
+	assert.Equal(t, 1, strings.Count(string(marshalled), `<complexContent mixed="true">`))
+}
+
+// TestMessagesError checks a problem converting the schema is returned rather than giving part of the messages
+func TestMessagesError(t *testing.T) {
+	x := parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="code">
+			<xs:simpleType>
+				<xs:restriction base="xs:string">
+					<xs:maxLength value="abc"/>
+				</xs:restriction>
+			</xs:simpleType>
+		</xs:element>
+		<xs:element name="name" type="xs:string"/>
+	</xs:schema>`)
+	messages, err := x.Messages("protobuf")
+	assert.EqualError(t, err, `maxLength "abc" is not a non negative integer`)
+	assert.Nil(t, messages)
+}
+
+// TestAnnotations checks documentation languages and appinfo survive unmarshal and marshal
+func TestAnnotations(t *testing.T) {
+	x := loadXSD(t, "./xsd/annotations.xsd")
+	if assert.Len(t, x.Annotations, 1) && assert.Len(t, x.Annotations[0].AppInfos, 1) {
+		assert.Equal(t, "Product catalogue", x.Annotations[0].Description(""))
+		assert.Equal(t, "urn:example:generator", x.Annotations[0].AppInfos[0].Source)
+		assert.Equal(t, "<package>catalogue</package>", x.Annotations[0].AppInfos[0].Content)
+	}
+	sizetype := x.SimpleTypes[0].Annotation
+	assert.Equal(t, "Taille du vêtement", sizetype.Description("fr"))
+	assert.Equal(t, "Garment size", sizetype.Description(""), "no documentation without a language so the first")
+	assert.Equal(t, "Small", x.SimpleTypes[0].Restriction.Enumerations[0].Annotation.Description(""))
+	assert.Equal(t, "The sequence is not a message item", x.ComplexTypes[0].Sequence.Annotation.Description(""))
+
+	marshalled, err := xml.Marshal(x)
+	if err != nil {
+		t.Fatalf("could not marshal XSD, got %v", err)
+	}
+	assert.Contains(t, string(marshalled), `xml:lang="fr"`)
+	if x, err = xsd.NewXSD(marshalled); err != nil {
+		t.Fatalf("could not unmarshal marshalled XSD, got %v", err)
+	}
+	assert.Equal(t, map[string]string{"en": "Garment size", "fr": "Taille du vêtement"}, x.SimpleTypes[0].Annotation.Descriptions())
+	assert.Equal(t, "The sequence is not a message item", x.ComplexTypes[0].Sequence.Annotation.Description(""))
+}
+
+// TestXSD11 checks assertions, alternatives and open content are modelled and walked
+func TestXSD11(t *testing.T) {
+	x := loadXSD(t, "./xsd/assertions.xsd")
+	assert.NoError(t, x.Resolve())
+	if assert.NotNil(t, x.DefaultOpenContent) {
+		assert.Equal(t, "suffix", x.DefaultOpenContent.Mode)
+		assert.Equal(t, "##other", x.DefaultOpenContent.Any.Namespace)
+	}
+	price := x.Elements[0].ComplexType.Sequence.Elements[1]
+	if assert.Len(t, price.Alternatives, 2) {
+		assert.Equal(t, xsd.QName{Local: "pricetype"}, price.Alternatives[0].TypeQName)
+		assert.Equal(t, "", price.Alternatives[1].Test, "the default alternative")
+	}
+	assert.Equal(t, "$value mod 2 = 0", x.SimpleTypes[0].Restriction.Assertions[0].Test)
+	assert.Equal(t, "interleave", x.ComplexTypes[1].OpenContent.Mode)
+	tree := x.ToStringP()
+	for _, s := range []string{"Assert: min le max", "Assertion: $value mod 2 = 0", "Alternative: @kind = 'retail' (pricetype)", "OpenContent: interleave", "DefaultOpenContent: suffix"} {
+		assert.Contains(t, tree, s)
+	}
+}
+
+// TestEffectiveContent checks derived complex types get the content of their base types
+func TestEffectiveContent(t *testing.T) {
+	x := loadXSD(t, "./xsd/inheritance.xsd")
+	assert.NoError(t, x.Resolve())
+	content := func(name string) *xsd.ContentModel {
+		xe, _ := x.LookupType(xsd.QName{Local: name})
+		cm, err := x.EffectiveContent(xe.(*xsd.ComplexType))
+		if err != nil {
+			t.Fatalf("could not get the content of %s, got %v", name, err)
+		}
+		return cm
+	}
+	elementNames := func(cm *xsd.ContentModel) (sSlice []string) {
+		for _, term := range cm.Terms() {
+			if e, isElement := term.(*xsd.Element); isElement {
+				sSlice = append(sSlice, e.Name)
+			}
+		}
+		return
+	}
+	attributeNames := func(cm *xsd.ContentModel) (sSlice []string) {
+		for _, a := range cm.Attributes {
+			sSlice = append(sSlice, a.Name)
+		}
+		return
+	}
+	employee := content("employeetype")
+	assert.Equal(t, []string{"name", "birthdate", "payroll"}, elementNames(employee))
+	assert.Equal(t, []string{"id", "created", "internal", "title"}, attributeNames(employee))
+	public := content("publicpersontype")
+	assert.Equal(t, []string{"name"}, elementNames(public))
+	assert.Equal(t, []string{"created", "title", "id"}, attributeNames(public))
+	taxed := content("taxedamounttype")
+	assert.Equal(t, xsd.QName{Namespace: xsd.XMLSchemaNamespace, Local: "decimal"}, taxed.SimpleType)
+	assert.Equal(t, []string{"currency", "rate"}, attributeNames(taxed))
+
+	messages, err := x.Messages("protobuf", xsd.Flatten())
+	if !assert.NoError(t, err) {
+		return
+	}
+	items := make(map[string][]string)
+	for _, m := range messages {
+		for _, mi := range m.MessageItems {
+			items[m.Name] = append(items[m.Name], mi.Name+" "+mi.Type)
+		}
+	}
+	assert.Equal(t, []string{"name string", "id string", "created google.protobuf.Timestamp", "internal bool", "birthdate google.protobuf.Timestamp", "title string"}, items["persontype"])
+	assert.Equal(t, []string{"name string", "birthdate google.protobuf.Timestamp", "id string", "created google.protobuf.Timestamp", "internal bool", "title string", "payroll string"}, items["employeetype"])
+	assert.Equal(t, []string{"created google.protobuf.Timestamp", "title string", "name string", "id string"}, items["publicpersontype"])
+	assert.Equal(t, []string{"taxedamounttype float", "currency string", "rate float"}, items["taxedamounttype"])
+
+	// Without flattening a restriction still keeps the base attributes and drops the prohibited ones
+	if messages, err = x.Messages("protobuf"); !assert.NoError(t, err) {
+		return
+	}
+	items = make(map[string][]string)
+	for _, m := range messages {
+		for _, mi := range m.MessageItems {
+			items[m.Name] = append(items[m.Name], mi.Name+" "+mi.Type)
+		}
+	}
+	assert.Equal(t, []string{"created google.protobuf.Timestamp", "title string", "name string", "id string"}, items["publicpersontype"])
+}
+
+// TestExtensionChoice checks a choice added by an extension is part of the content of the derived type
+func TestExtensionChoice(t *testing.T) {
+	x := parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:complexType name="basetype">
+			<xs:sequence>
+				<xs:element name="a" type="xs:string"/>
+			</xs:sequence>
+		</xs:complexType>
+		<xs:complexType name="derivedtype">
+			<xs:complexContent>
+				<xs:extension base="basetype">
+					<xs:choice>
+						<xs:element name="b" type="xs:string"/>
+						<xs:element name="c" type="xs:int"/>
+					</xs:choice>
+				</xs:extension>
+			</xs:complexContent>
+		</xs:complexType>
+		<xs:element name="d" type="derivedtype"/>
+	</xs:schema>`)
+	assert.NoError(t, x.Resolve())
+	assert.Contains(t, x.ToStringP(), "Choice")
+	cm, err := x.EffectiveContent(x.ComplexTypes[1])
+	if assert.NoError(t, err) {
+		assert.Len(t, cm.Particles, 2)
+		assert.IsType(t, &xsd.Choice{}, cm.Particles[1])
+	}
+	assert.NoError(t, x.Validate(strings.NewReader(`<d><a>x</a><b>y</b></d>`)))
+	assert.Error(t, x.Validate(strings.NewReader(`<d><a>x</a></d>`)))
+
+	messages, err := x.Messages("protobuf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	var items []string
+	for _, m := range messages {
+		if m.Name == "derivedtype" {
+			for _, mi := range m.MessageItems {
+				items = append(items, mi.Name)
+			}
+		}
+	}
+	assert.Equal(t, []string{"derivedtype", "b", "c"}, items)
+}
+
+// TestBuiltinTypes checks the built in type hierarchy and the primitive types of simple types
+func TestBuiltinTypes(t *testing.T) {
+	bt, found := xsd.LookupBuiltinType("positiveInteger")
+	if assert.True(t, found) {
+		var bases []string
+		for base := bt.BaseType(); base != nil; base = base.BaseType() {
+			bases = append(bases, base.Name)
+		}
+		assert.Equal(t, []string{"nonNegativeInteger", "integer", "decimal", "anyAtomicType", "anySimpleType", "anyType"}, bases)
+		assert.Equal(t, "decimal", bt.PrimitiveType().Name)
+		assert.Equal(t, "1", bt.MinInclusive)
+		assert.True(t, bt.DerivesFrom("integer"))
+		assert.False(t, bt.DerivesFrom("string"))
+	}
+	bt, _ = xsd.LookupBuiltinType("IDREFS")
+	assert.True(t, bt.IsList())
+	assert.Nil(t, bt.PrimitiveType())
+	_, found = xsd.LookupBuiltinType("datetime")
+	assert.False(t, found)
+
+	x := loadXSD(t, "./xsd/builtins.xsd")
+	assert.NoError(t, x.Resolve())
+	primitive := func(name string) (string, error) {
+		xe, _ := x.LookupType(xsd.QName{Local: name})
+		p, err := x.PrimitiveType(xe.(*xsd.SimpleType))
+		if p == nil {
+			return "", err
+		}
+		return p.Name, err
+	}
+	p, err := primitive("score")
+	assert.NoError(t, err)
+	assert.Equal(t, "decimal", p)
+	_, err = primitive("reading")
+	assert.Error(t, err, "a union of float and decimal doesn't have a primitive type")
+}
+
+// TestTypeCycles checks recursive types and elements are reported and their message items marked
+func TestTypeCycles(t *testing.T) {
+	x := loadXSD(t, "./xsd/recursion.xsd")
+	assert.NoError(t, x.Resolve())
+	var cycles []string
+	for _, c := range x.TypeCycles() {
+		cycles = append(cycles, c.String())
+	}
+	assert.Equal(t, []string{
+		"element folder -> element folder",
+		"complexType parttype -> complexType parttype",
+		"complexType persontype -> complexType companytype -> complexType persontype",
+	}, cycles)
+
+	messages, err := x.Messages("protobuf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	recursive := make(map[string]bool)
+	for _, m := range messages {
+		for _, mi := range m.MessageItems {
+			recursive[m.Name+"."+mi.Name] = mi.Recursive
+		}
+	}
+	assert.True(t, recursive["parttype.part"])
+	assert.True(t, recursive["persontype.employer"])
+	assert.True(t, recursive["folder.folder"])
+	assert.False(t, recursive["folder.file"])
+	assert.False(t, recursive["assembly.assembly"], "assembly isn't part of the cycle it leads to")
+
+	// A derivation that's a cycle
+	x = parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:simpleType name="a"><xs:restriction base="b"/></xs:simpleType>
+		<xs:simpleType name="b"><xs:restriction base="a"/></xs:simpleType>
+		<xs:complexType name="c"><xs:complexContent><xs:extension base="c"/></xs:complexContent></xs:complexType>
+	</xs:schema>`)
+	_, err = x.PrimitiveType(x.SimpleTypes[0])
+	assert.ErrorIs(t, err, xsd.ErrDerivedFromItself)
+	_, err = x.EffectiveContent(x.ComplexTypes[0])
+	assert.ErrorIs(t, err, xsd.ErrDerivedFromItself)
+	var constraints []string
+	for _, d := range x.Check() {
+		constraints = append(constraints, d.Constraint)
+	}
+	assert.Contains(t, constraints, "st-props-correct.2")
+	assert.Contains(t, constraints, "ct-props-correct.3")
+}
+
+// TestCheck checks the schema component constraints are reported
+func TestCheck(t *testing.T) {
+	x := loadXSD(t, "./xsd/check/invalid.xsd")
+	var constraints []string
+	for _, d := range x.Check() {
+		t.Log(d.String())
+		constraints = append(constraints, d.Constraint)
+	}
+	assert.ElementsMatch(t, []string{
+		"sch-props-correct.2",
+		"cos-applicable-facets",
+		"minInclusive-less-than-equal-to-maxInclusive",
+		"length-minLength-maxLength",
+		"s4s-att-invalid-value",
+		"cos-ct-extends.1.1",
+		"p-props-correct.2.1",
+		"cos-nonambig",
+		"cos-element-consistent",
+		"src-element.2.1",
+		"cos-nonambig",
+		"src-attribute.1",
+		"cos-nonambig",
+		"cos-nonambig",
+	}, constraints)
+
+	for _, name := range []string{"shiporder_named_types.xsd", "inheritance.xsd", "substitution.xsd"} {
+		assert.Empty(t, loadXSD(t, "./xsd/"+name).Check(), name)
+	}
+}
+
+// TestValidate checks instance documents against a schema
+func TestValidate(t *testing.T) {
+	x := loadXSD(t, "./xsd/instance/order.xsd")
+	validate := func(name string) error {
+		f, err := os.Open("./xsd/instance/" + name)
+		if err != nil {
+			t.Fatalf("could not open the document, got %v", err)
+		}
+		defer f.Close()
+		return x.Validate(f)
+	}
+	assert.NoError(t, validate("order.xml"))
+
+	err := validate("order_invalid.xml")
+	var errs xsd.ValidationErrors
+	if !assert.ErrorAs(t, err, &errs) {
+		return
+	}
+	var messages []string
+	for _, ve := range errs {
+		t.Log(ve.Error())
+		messages = append(messages, ve.Error())
+	}
+	assert.Equal(t, []string{
+		`line 2, column 1: element order: attribute date: value "29/02/2024" isn't a valid date`,
+		`line 2, column 1: element order: attribute status isn't allowed`,
+		`line 2, column 1: element order: attribute id is required`,
+		`line 5, column 5: element order: element phone isn't expected here`,
+		`line 6, column 5: element item: attribute gift: value "yes" isn't a valid boolean`,
+		`line 7, column 9: element sku: value "123-ab" doesn't match the pattern`,
+		`line 8, column 9: element quantity: value 150 doesn't meet maxInclusive 99`,
+		`line 13, column 9: element quantity: value 0 is less than 1, the minimum of positiveInteger`,
+		`line 14, column 9: element price: value "abc" isn't a valid decimal`,
+		`line 15, column 9: element sizes: value "x" isn't a valid positiveInteger`,
+		`line 17, column 5: element item: the content is incomplete, more elements are expected`,
+		`line 21, column 5: element notes: element isn't allowed in order`,
+	}, messages)
+
+	// The first problem can be found with errors.As, each one has its place in the document and the component it breaks
+	var ve *xsd.ValidationError
+	if assert.ErrorAs(t, err, &ve) {
+		assert.Equal(t, "/order", ve.Path)
+		assert.IsType(t, &xsd.Attribute{}, ve.Component)
+	}
+	invalid, err := os.ReadFile("./xsd/instance/order_invalid.xml")
+	if err != nil {
+		t.Fatalf("could not read the document, got %v", err)
+	}
+	assert.Equal(t, "/order/item/quantity", errs[6].Path)
+	assert.IsType(t, &xsd.Restriction{}, errs[6].Component)
+	assert.Equal(t, "/order/item[2]/quantity", errs[7].Path)
+	assert.Equal(t, int64(strings.Index(string(invalid), "<quantity>0")), errs[7].Offset)
+	assert.IsType(t, &xsd.Element{}, errs[7].Component)
+	assert.Equal(t, "/order/item[3]", errs[10].Path)
+
+	// Each item after the third is reported as it's read
+	item := "<item><sku>123-AB</sku><quantity>1</quantity><price>2.50</price></item>"
+	document := io.MultiReader(strings.NewReader("<order id='o1'><customer>c</customer><email>e</email>"),
+		strings.NewReader(strings.Repeat(item, 1000)), strings.NewReader("</order>"))
+	reported := 0
+	assert.NoError(t, x.ValidateStream(document, func(ve *xsd.ValidationError) {
+		assert.Equal(t, "element item isn't expected here", ve.Message)
+		reported++
+	}))
+	assert.Equal(t, 997, reported)
+
+	assert.Error(t, x.Validate(strings.NewReader("<invoice/>")))
+	assert.Error(t, x.Validate(strings.NewReader("<order id='o1'><customer>")), "the document isn't complete")
+
+	// Large maxOccurs are counted rather than unrolled
+	counted := parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="list">
+			<xs:complexType>
+				<xs:sequence maxOccurs="unbounded">
+					<xs:element name="a" maxOccurs="500"/>
+					<xs:sequence minOccurs="0" maxOccurs="200">
+						<xs:element name="b"/>
+						<xs:element name="c" minOccurs="0"/>
+					</xs:sequence>
+					<xs:element name="d"/>
+				</xs:sequence>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`)
+	list := func(parts ...string) io.Reader {
+		return strings.NewReader("<list>" + strings.Join(parts, "") + "</list>")
+	}
+	assert.NoError(t, counted.Validate(list(strings.Repeat("<a/>", 500), strings.Repeat("<b/><c/>", 200), "<d/>", "<a/><d/>")))
+	assert.Error(t, counted.Validate(list(strings.Repeat("<a/>", 600), "<d/>")))
+	assert.Error(t, counted.Validate(list("<a/>", strings.Repeat("<b/>", 201), "<d/>")))
+	assert.NoError(t, counted.Validate(list(strings.Repeat("<a/>", 300), "<d/>", strings.Repeat("<a/>", 300), "<d/>")), "the count starts again")
+}
+
+// heapReader reads a document and samples the heap once it has all been read
+type heapReader struct {
+	io.Reader
+	heap uint64
+}
+
+func (r *heapReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		r.heap = m.HeapAlloc
+	}
+	return n, err
+}
+
+// TestValidateStreamMemory checks the memory used by validation doesn't grow with the number of elements
+func TestValidateStreamMemory(t *testing.T) {
+	x := parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:element name="list">
+			<xs:complexType>
+				<xs:sequence>
+					<xs:element name="a" maxOccurs="unbounded"/>
+					<xs:element name="b" minOccurs="0" maxOccurs="1000000"/>
+				</xs:sequence>
+			</xs:complexType>
+		</xs:element>
+	</xs:schema>`)
+	document := "<list>" + strings.Repeat("<a/>", 400000) + strings.Repeat("<b/>", 400000) + "</list>"
+	var m runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m)
+	r := &heapReader{Reader: strings.NewReader(document)}
+	assert.NoError(t, x.ValidateStream(r, func(ve *xsd.ValidationError) {
+		t.Error(ve)
+	}))
+	assert.Less(t, int64(r.heap)-int64(m.HeapAlloc), int64(4<<20), "the heap grows with the document")
+}
+
 func compareDefinitions(t *testing.T, xsd1 *xsd.XSD, xsd2 *xsd.XSD) bool {
-	if assert.Equal(t, xsd1.Import, xsd2.Import) {
+	if assert.Equal(t, xsd1.Imports, xsd2.Imports) {
 		if assert.Equal(t, xsd1.ComplexTypes, xsd2.ComplexTypes) {
 			return true
 		}
@@ -225,3 +1167,169 @@ func compareDefinitions(t *testing.T, xsd1 *xsd.XSD, xsd2 *xsd.XSD) bool {
 ////		}
 ////	}
 ////}
+
+// TestRegexp checks XSD regular expressions are translated to Go regular expressions that match the same values
+func TestRegexp(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{`[a-z-[aeiou]]+`, []string{"xyz"}, []string{"xyza", "XYZ"}},
+		{`[\i-[:]][\c-[:]]*`, []string{"_a.b-1", "été"}, []string{"a:b", "1a"}},
+		{`\p{IsBasicLatin}+`, []string{"abc"}, []string{"é"}},
+		{`[\P{IsBasicLatin}0-9]+`, []string{"é1"}, []string{"a"}},
+		{`\d{3}-[A-Z]{2}`, []string{"123-AB"}, []string{"x123-AB", "123-AB\n"}},
+		{`a^b$`, []string{"a^b$"}, []string{"ab"}},
+		{`\S+\s\w`, []string{"ab x"}, []string{"ab !"}},
+		{`(ab|c){2,3}`, []string{"abc", "ccab"}, []string{"c", "abababab"}},
+		{`.`, []string{"a"}, []string{"\n"}},
+	} {
+		re, err := xsd.CompileRegexp(c.pattern)
+		if !assert.NoError(t, err, c.pattern) {
+			continue
+		}
+		for _, value := range c.match {
+			assert.True(t, re.MatchString(value), "%s should match %q", c.pattern, value)
+		}
+		for _, value := range c.noMatch {
+			assert.False(t, re.MatchString(value), "%s shouldn't match %q", c.pattern, value)
+		}
+	}
+	translated, err := xsd.TranslateRegexp(`[a-z-[aeiou]]\d`)
+	assert.NoError(t, err)
+	assert.Equal(t, `^(?:[b-df-hj-np-tv-z]\p{Nd})$`, translated)
+
+	for pattern, offset := range map[string]int{`a??`: 2, `[a-z`: 0, `(a`: 2, `a)`: 1, `\b`: 0, `\p{IsKlingon}`: 2, `[z-a]`: 4, `(?:a)`: 1} {
+		_, err := xsd.CompileRegexp(pattern)
+		var re *xsd.RegexpError
+		if assert.ErrorAs(t, err, &re, pattern) {
+			assert.Equal(t, offset, re.Offset, pattern)
+		}
+	}
+}
+
+// TestValues checks values of built in types are parsed to their value space and ordered
+func TestValues(t *testing.T) {
+	compare := func(typeName, a, b string) (int, bool) {
+		va, err := xsd.ParseValue(typeName, a)
+		if !assert.NoError(t, err) {
+			return 0, false
+		}
+		vb, err := xsd.ParseValue(typeName, b)
+		if !assert.NoError(t, err) {
+			return 0, false
+		}
+		return va.Compare(vb)
+	}
+	for _, c := range []struct {
+		typeName, a, b string
+		c              int
+		ordered        bool
+	}{
+		{"decimal", "1.50", " +1.5 ", 0, true},
+		{"positiveInteger", "10", "9", 1, true},
+		{"double", "INF", "1E308", 1, true},
+		{"float", "NaN", "1", 0, false},
+		{"dateTime", "2024-01-01T12:00:00Z", "2024-01-01T13:00:00+01:00", 0, true},
+		{"dateTime", "2024-01-01T23:00:00-02:00", "2024-01-02T00:00:00Z", 1, true},
+		{"date", "2024-01-01", "2024-01-02Z", -1, true},
+		{"date", "2024-01-01", "2024-01-01Z", 0, false},
+		{"time", "24:00:00", "00:00:00", 0, true},
+		{"gMonthDay", "--02-29", "--03-01", -1, true},
+		{"gYear", "-0001", "2024", -1, true},
+		{"duration", "PT36H", "P1D", 1, true},
+		{"duration", "P1M", "P30D", 0, false},
+		{"duration", "P1Y", "P365D", 0, false},
+		{"duration", "P1Y", "P364D", 1, true},
+		{"duration", "-P1D", "PT0S", -1, true},
+		{"string", "a", "b", 0, false},
+		{"token", " a  b ", "a b", 0, true},
+	} {
+		result, ordered := compare(c.typeName, c.a, c.b)
+		assert.Equal(t, c.ordered, ordered, "%s %s %s", c.typeName, c.a, c.b)
+		assert.Equal(t, c.c, result, "%s %s %s", c.typeName, c.a, c.b)
+	}
+
+	v, err := xsd.ParseValue("dateTime", "2024-02-29T10:30:15.25+05:30")
+	if assert.NoError(t, err) {
+		assert.Equal(t, &xsd.DateTime{Year: 2024, Month: 2, Day: 29, Hour: 10, Minute: 30, Second: big.NewRat(61, 4), HasTimezone: true, Timezone: 330}, v.DateTime)
+	}
+	v, err = xsd.ParseValue("base64Binary", "AQID")
+	if assert.NoError(t, err) {
+		assert.Equal(t, []byte{1, 2, 3}, v.Bytes)
+	}
+	v, err = xsd.ParseValue("QName", "xs:string")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "xs", v.Prefix)
+		assert.Equal(t, "string", v.QName.Local)
+	}
+	v, err = xsd.ParseValue("IDREFS", "a b")
+	if assert.NoError(t, err) {
+		assert.Len(t, v.Items, 2)
+	}
+	for typeName, lexical := range map[string]string{
+		"date": "2023-02-29", "time": "24:00:01", "dateTime": "2024-01-01T12:00:00+15:00", "duration": "P1YT",
+		"gYear": "02024", "hexBinary": "ABC", "NCName": "a:b", "language": "englishlanguage", "byte": "128",
+	} {
+		_, err := xsd.ParseValue(typeName, lexical)
+		assert.Error(t, err, "%s %s", typeName, lexical)
+	}
+
+	// The bounds of simple content are values of the content's type
+	x := parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:complexType name="event">
+			<xs:simpleContent>
+				<xs:extension base="xs:date">
+					<xs:attribute name="name" type="xs:string"/>
+				</xs:extension>
+			</xs:simpleContent>
+		</xs:complexType>
+		<xs:complexType name="recent">
+			<xs:simpleContent>
+				<xs:restriction base="event">
+					<xs:minInclusive value="2020-01-01"/>
+				</xs:restriction>
+			</xs:simpleContent>
+		</xs:complexType>
+		<xs:complexType name="impossible">
+			<xs:simpleContent>
+				<xs:restriction base="event">
+					<xs:minInclusive value="2021-01-01"/>
+					<xs:maxExclusive value="2020-06-30"/>
+					<xs:maxLength value="10"/>
+				</xs:restriction>
+			</xs:simpleContent>
+		</xs:complexType>
+		<xs:element name="recent" type="recent"/>
+	</xs:schema>`)
+	assert.NoError(t, x.Validate(strings.NewReader(`<recent name="launch">2021-03-04</recent>`)))
+	assert.ErrorContains(t, x.Validate(strings.NewReader(`<recent name="launch">1999-01-01</recent>`)), "doesn't meet minInclusive 2020-01-01")
+	var constraints []string
+	for _, d := range x.Check() {
+		constraints = append(constraints, d.Constraint)
+	}
+	assert.ElementsMatch(t, []string{"minInclusive-less-than-equal-to-maxExclusive", "cos-applicable-facets"}, constraints)
+
+	// Enumerated values are compared as values of the primitive type
+	x = parseXSD(t, `<xs:schema xmlns:xs="http://www.w3.org/2001/XMLSchema">
+		<xs:simpleType name="price">
+			<xs:restriction base="xs:decimal">
+				<xs:enumeration value="1.0"/>
+				<xs:enumeration value="2.5"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:simpleType name="count">
+			<xs:restriction base="xs:integer">
+				<xs:enumeration value="5"/>
+			</xs:restriction>
+		</xs:simpleType>
+		<xs:element name="price" type="price"/>
+		<xs:element name="count" type="count"/>
+	</xs:schema>`)
+	assert.NoError(t, x.Validate(strings.NewReader(`<price>1</price>`)))
+	assert.NoError(t, x.Validate(strings.NewReader(`<price>2.50</price>`)))
+	assert.ErrorContains(t, x.Validate(strings.NewReader(`<price>3</price>`)), "isn't one of the enumerated values")
+	assert.NoError(t, x.Validate(strings.NewReader(`<count>+5</count>`)))
+	assert.ErrorContains(t, x.Validate(strings.NewReader(`<count>6</count>`)), "isn't one of the enumerated values")
+}